@@ -2,164 +2,103 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
-	"sync"
+	"strings"
 	"syscall"
-	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
-	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
-	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/app"
 	"github.com/Divyanth2468/video-ad-tracker/internal/config"
-	logging "github.com/Divyanth2468/video-ad-tracker/internal/logs"
-	"github.com/Divyanth2468/video-ad-tracker/internal/worker"
-	"github.com/gin-gonic/gin"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
 	"github.com/joho/godotenv"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/collectors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
-var (
-	httpRequestsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total HTTP requests",
-		},
-		[]string{"method", "path", "status"},
-	)
-	httpRequestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Request duration in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"path"},
-	)
-)
-
-func PrometheusMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
-		duration := time.Since(start)
-
-		status := fmt.Sprintf("%d", c.Writer.Status())
-		httpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath(), status).Inc()
-		httpRequestDuration.WithLabelValues(c.FullPath()).Observe(duration.Seconds())
-	}
-}
+// envFile holds settings for local runs. Variables set in the process
+// environment take precedence over it.
+const envFile = ".env"
 
 func main() {
-	logging.InitLogger()
-	logger := logging.Logger
-	_ = godotenv.Load(".env")
-
-	config.InitDB()
-	defer config.DB.Close()
+	environ := environment()
+	_ = godotenv.Load(envFile)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, opts, err := config.Load(os.Args[1:], lookupEnv(environ))
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	redisAddr := os.Getenv("REDIS_ADDR")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	redisDBStr := os.Getenv("REDIS_DB")
-	workerCountStr := os.Getenv("WORKER_COUNT")
-
-	if redisAddr == "" {
-		logger.Fatal("REDIS_ADDR is required")
+	if opts.PrintConfig && cfg != nil {
+		fmt.Print(cfg.Redacted())
 	}
-	redisDB, err := strconv.Atoi(redisDBStr)
 	if err != nil {
-		logger.Fatal("Invalid REDIS_DB value")
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
-	workerCount, err := strconv.Atoi(workerCountStr)
-	if err != nil {
-		logger.Warn("Invalid WORKER_COUNT. Defaulting to 4")
-		workerCount = 4
+	if opts.PrintConfig {
+		return
 	}
 
-	redisClient := analytics.NewRedisAnalytics(redisAddr, redisPassword, redisDB)
+	logger := logs.New(cfg.Log.File)
+	level, _ := logrus.ParseLevel(cfg.Log.Level)
+	logger.SetLevel(level)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	var wg sync.WaitGroup
-
-	worker.StartQueueWorker(ctx, redisClient.Client, config.DB, redisClient, &wg, workerCount)
-
-	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration)
-	ads.InitAdMetrics()
-	if err := prometheus.Register(collectors.NewGoCollector()); err != nil {
-		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
-			log.Fatalf("could not register Go collector: %v", err)
-		}
+	application, err := app.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialise application")
+	}
+	application.LoadConfig = func() (*config.Config, error) {
+		cfg, _, err := config.Load(os.Args[1:], lookupEnv(environ))
+		return cfg, err
+	}
+	if err := application.Start(); err != nil {
+		logger.WithError(err).Fatal("Server failed to start")
 	}
 
-	r := gin.Default()
-	r.Use(PrometheusMiddleware())
-	r.Static("/assets", "./web/assets")
-	r.LoadHTMLFiles("web/index.html")
-
-	r.GET("/", func(c *gin.Context) {
-		c.HTML(200, "index.html", nil)
-	})
-
-	r.GET("/ads", ads.GetAdHandler(config.DB))
-	r.POST("/ads/impression", func(c *gin.Context) {
-		var payload struct {
-			AdID string `json:"ad_id" binding:"required"`
-		}
-		if err := c.ShouldBindJSON(&payload); err != nil {
-			c.JSON(400, gin.H{"error": "Missing or invalid ad_id"})
-			return
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range quit {
+		if sig != syscall.SIGHUP {
+			break
 		}
-		if err := redisClient.IncrementImpression(payload.AdID); err != nil {
-			c.JSON(500, gin.H{"error": "Failed to record impression"})
-			return
+		if _, err := application.Reload(); err != nil {
+			logger.WithError(err).Error("Configuration reload rejected")
 		}
-		c.Status(204)
-	})
-
-	clickHandler := &clicks.ClickHandler{DB: config.DB, Redis: redisClient}
-	r.POST("/ads/click", clickHandler.HandlerClick)
-	r.GET("/ads/analytics", redisClient.GetAnalyticsHandler)
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
 	}
 
-	go func() {
-		logger.WithField("port", port).Info("Server starting")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.WithError(err).Fatal("Server failed to start")
-		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
 	logger.Info("Graceful shutdown initiated...")
-	cancel()
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		logger.WithError(err).Fatal("Server forced to shutdown")
+	if err := application.Stop(shutdownCtx); err != nil {
+		logger.WithError(err).Error("Server forced to shutdown")
 	}
 
-	wg.Wait()
+	logger.Info("Server shutdown complete")
+}
 
-	if err := redisClient.Client.Close(); err != nil {
-		logger.WithError(err).Error("Error closing Redis client")
+// environment returns the variables the process was started with, before
+// envFile is loaded into it.
+func environment() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
 	}
+	return env
+}
 
-	logger.Info("Server shutdown complete")
+// lookupEnv reads envFile afresh, so that a reload picks up its edits, and
+// looks variables up in environ first and in the file second.
+func lookupEnv(environ map[string]string) func(string) (string, bool) {
+	file, _ := godotenv.Read(envFile)
+	return func(key string) (string, bool) {
+		if v, ok := environ[key]; ok {
+			return v, true
+		}
+		v, ok := file[key]
+		return v, ok
+	}
 }
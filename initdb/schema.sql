@@ -1,17 +1,119 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  click_window INTERVAL NOT NULL DEFAULT '7 days',
+  view_window INTERVAL NOT NULL DEFAULT '1 day',
+  cpm FLOAT NOT NULL DEFAULT 0.0,
+  cpc FLOAT NOT NULL DEFAULT 0.0
+);
+
+-- Columns added after the table was first created. Databases created from an
+-- earlier schema gain them when this file is applied again; on new databases
+-- these are no-ops.
+ALTER TABLE campaigns
+  ADD COLUMN IF NOT EXISTS click_window INTERVAL NOT NULL DEFAULT '7 days',
+  ADD COLUMN IF NOT EXISTS view_window INTERVAL NOT NULL DEFAULT '1 day',
+  ADD COLUMN IF NOT EXISTS cpm FLOAT NOT NULL DEFAULT 0.0,
+  ADD COLUMN IF NOT EXISTS cpc FLOAT NOT NULL DEFAULT 0.0;
+
 CREATE TABLE IF NOT EXISTS ads (
   id UUID PRIMARY KEY,
+  campaign_id UUID REFERENCES campaigns(id),
   video_url TEXT NOT NULL,
   target_url TEXT NOT NULL
 );
 
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS campaign_id UUID REFERENCES campaigns(id);
+
+CREATE TABLE IF NOT EXISTS experiments (
+  id UUID PRIMARY KEY,
+  campaign_id UUID REFERENCES campaigns(id),
+  name TEXT NOT NULL,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ended_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS experiment_arms (
+  experiment_id UUID REFERENCES experiments(id),
+  name TEXT NOT NULL,
+  ad_id UUID NOT NULL REFERENCES ads(id),
+  weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+  PRIMARY KEY (experiment_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS impressions (
+  id UUID PRIMARY KEY,
+  ad_id UUID REFERENCES ads(id),
+  viewer_id TEXT,
+  experiment_id UUID,
+  arm TEXT,
+  timestamp TIMESTAMPTZ NOT NULL,
+  ip_address TEXT
+);
+
+ALTER TABLE impressions
+  ADD COLUMN IF NOT EXISTS viewer_id TEXT,
+  ADD COLUMN IF NOT EXISTS experiment_id UUID,
+  ADD COLUMN IF NOT EXISTS arm TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_impressions_viewer ON impressions (viewer_id, timestamp);
+
 CREATE TABLE IF NOT EXISTS click_events (
   id UUID PRIMARY KEY,
   ad_id UUID REFERENCES ads(id),
+  impression_id UUID REFERENCES impressions(id),
+  viewer_id TEXT,
+  experiment_id UUID,
+  arm TEXT,
   timestamp TIMESTAMPTZ NOT NULL,
   ip_address TEXT,
-  video_playback_time FLOAT
+  video_playback_time FLOAT,
+  user_agent TEXT,
+  fraud_score FLOAT DEFAULT 0.0,
+  fraud_reasons TEXT[],
+  is_valid BOOLEAN DEFAULT TRUE
 );
 
+ALTER TABLE click_events
+  ADD COLUMN IF NOT EXISTS impression_id UUID REFERENCES impressions(id),
+  ADD COLUMN IF NOT EXISTS viewer_id TEXT,
+  ADD COLUMN IF NOT EXISTS experiment_id UUID,
+  ADD COLUMN IF NOT EXISTS arm TEXT,
+  ADD COLUMN IF NOT EXISTS user_agent TEXT,
+  ADD COLUMN IF NOT EXISTS fraud_score FLOAT DEFAULT 0.0,
+  ADD COLUMN IF NOT EXISTS fraud_reasons TEXT[],
+  ADD COLUMN IF NOT EXISTS is_valid BOOLEAN DEFAULT TRUE;
+
+CREATE INDEX IF NOT EXISTS idx_click_events_impression ON click_events (impression_id);
+CREATE INDEX IF NOT EXISTS idx_click_events_viewer ON click_events (viewer_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS video_events (
+  id UUID PRIMARY KEY,
+  ad_id UUID REFERENCES ads(id),
+  event_type TEXT NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL,
+  ip_address TEXT,
+  playback_time FLOAT
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_events_ad_type ON video_events (ad_id, event_type);
+
+CREATE TABLE IF NOT EXISTS conversions (
+  id UUID PRIMARY KEY,
+  order_id TEXT,
+  click_id UUID REFERENCES click_events(id),
+  impression_id UUID REFERENCES impressions(id),
+  ad_id UUID REFERENCES ads(id),
+  campaign_id UUID REFERENCES campaigns(id),
+  viewer_id TEXT,
+  attribution TEXT NOT NULL,
+  value FLOAT DEFAULT 0.0,
+  timestamp TIMESTAMPTZ NOT NULL,
+  ip_address TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversions_ad ON conversions (ad_id);
+
 CREATE TABLE IF NOT EXISTS ad_analytics (
     ad_id UUID PRIMARY KEY,
     total_clicks INTEGER DEFAULT 0,
@@ -20,3 +122,9 @@ CREATE TABLE IF NOT EXISTS ad_analytics (
     ctr FLOAT DEFAULT 0.0,
     updated_at TIMESTAMP DEFAULT NOW()
 );
+
+ALTER TABLE ad_analytics
+  ADD COLUMN IF NOT EXISTS conversions INTEGER DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS conversion_value FLOAT DEFAULT 0.0,
+  ADD COLUMN IF NOT EXISTS conversion_rate FLOAT DEFAULT 0.0,
+  ADD COLUMN IF NOT EXISTS cost_per_conversion FLOAT DEFAULT 0.0;
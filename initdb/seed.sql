@@ -1,5 +1,15 @@
-INSERT INTO ads (id, video_url, target_url) VALUES
-  ('11111111-1111-1111-1111-111111111111', '/assets/ads/ad1.mp4', 'https://google.com'),
-  ('22222222-2222-2222-2222-222222222222', '/assets/ads/ad2.mp4', 'https://product2.com'),
-  ('33333333-3333-3333-3333-333333333333', '/assets/ads/ad3.mp4', 'https://product3.com');
+INSERT INTO campaigns (id, name, click_window, view_window, cpm, cpc) VALUES
+  ('aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa', 'Search Launch', '30 days', '1 day', 0.0, 0.50),
+  ('bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb', 'Product Showcase', '7 days', '3 days', 12.00, 0.0);
 
+INSERT INTO ads (id, campaign_id, video_url, target_url) VALUES
+  ('11111111-1111-1111-1111-111111111111', 'aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa', '/assets/ads/ad1.mp4', 'https://google.com'),
+  ('22222222-2222-2222-2222-222222222222', 'bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb', '/assets/ads/ad2.mp4', 'https://product2.com/?ref={click_id}'),
+  ('33333333-3333-3333-3333-333333333333', 'bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb', '/assets/ads/ad3.mp4', 'https://product3.com');
+
+INSERT INTO experiments (id, campaign_id, name) VALUES
+  ('eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee', 'bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb', 'Showcase creative test');
+
+INSERT INTO experiment_arms (experiment_id, name, ad_id, weight) VALUES
+  ('eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee', 'control', '22222222-2222-2222-2222-222222222222', 1),
+  ('eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee', 'variant', '33333333-3333-3333-3333-333333333333', 1);
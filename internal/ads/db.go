@@ -0,0 +1,52 @@
+package ads
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GetAdByID loads a single ad by its ID.
+func GetAdByID(ctx context.Context, db *pgxpool.Pool, id string) (Ad, error) {
+	var ad Ad
+	err := db.QueryRow(ctx,
+		`SELECT id, COALESCE(campaign_id::text, ''), video_url, target_url FROM ads WHERE id = $1`, id).
+		Scan(&ad.ID, &ad.CampaignID, &ad.VideoURL, &ad.TargetURL)
+	return ad, err
+}
+
+// ListAds loads every ad.
+func ListAds(ctx context.Context, db *pgxpool.Pool) ([]Ad, error) {
+	rows, err := db.Query(ctx, `SELECT id, COALESCE(campaign_id::text, ''), video_url, target_url FROM ads`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ads []Ad
+	for rows.Next() {
+		var ad Ad
+		if err := rows.Scan(&ad.ID, &ad.CampaignID, &ad.VideoURL, &ad.TargetURL); err != nil {
+			return nil, err
+		}
+		ads = append(ads, ad)
+	}
+	return ads, rows.Err()
+}
+
+// Pricing is what the ad's campaign pays per thousand impressions and per valid click.
+type Pricing struct {
+	CPM float64
+	CPC float64
+}
+
+// GetPricing loads the pricing of the ad's campaign. Ads without a campaign cost nothing.
+func GetPricing(ctx context.Context, db *pgxpool.Pool, adID string) (Pricing, error) {
+	var p Pricing
+	err := db.QueryRow(ctx,
+		`SELECT COALESCE(cp.cpm, 0), COALESCE(cp.cpc, 0)
+		 FROM ads a LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+		 WHERE a.id = $1`, adID).
+		Scan(&p.CPM, &p.CPC)
+	return p, err
+}
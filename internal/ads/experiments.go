@@ -0,0 +1,34 @@
+package ads
+
+import (
+	"context"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/experiments"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// runningExperiments loads the running experiments. Serving carries on
+// without them if they cannot be loaded.
+func runningExperiments(ctx context.Context, db *pgxpool.Pool, logger logrus.FieldLogger) []experiments.Experiment {
+	running, err := experiments.Running(ctx, db)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to load experiments. Serving without them")
+	}
+	return running
+}
+
+// assignArm tags ad with the viewer's arm when ad is in a running experiment
+// and returns the ID of the ad the viewer should see, which differs from
+// ad.ID when the viewer is assigned another arm.
+func assignArm(running []experiments.Experiment, ad *Ad, viewerID string) string {
+	exp, ok := experiments.ForAd(running, ad.ID)
+	if !ok {
+		return ad.ID
+	}
+	arm := exp.Assign(viewerID)
+	if arm.AdID == ad.ID {
+		ad.ExperimentID, ad.Arm = exp.ID, arm.Name
+	}
+	return arm.AdID
+}
@@ -4,40 +4,49 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
 )
 
-func GetAdHandler(db *pgxpool.Pool) gin.HandlerFunc {
+// GetAdHandler lists the ads eligible for the viewer, each with its own
+// tracking token. The ad picked by strategy comes first.
+func GetAdHandler(db *pgxpool.Pool, signer *signing.Signer, strategy Strategy, metrics *Metrics, logger logrus.FieldLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		logger := logs.Logger.WithField("path", "/ads")
+		logger := logger.WithField("path", "/ads")
 
-		rows, err := db.Query(c, "SELECT id, video_url, target_url FROM ads")
+		viewerID := ViewerID(c)
+		running := runningExperiments(c, db, logger)
+
+		all, err := ListAds(c, db)
 		if err != nil {
 			logger.WithError(err).Error("Failed to query ads")
-			adsRequestCounter.WithLabelValues("500").Inc()
+			metrics.adsRequestCounter.WithLabelValues("500").Inc()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ads"})
 			return
 		}
-		defer rows.Close()
 
 		var ads []Ad
-		for rows.Next() {
-			var ad Ad
-			if err := rows.Scan(&ad.ID, &ad.VideoURL, &ad.TargetURL); err != nil {
-				logger.WithError(err).Warn("Failed to scan ad row")
+		for _, ad := range eligible(all, running, viewerID) {
+			if _, ad.Token, err = signer.IssueClaims(ad.Claims(viewerID)); err != nil {
+				logger.WithError(err).WithField("adId", ad.ID).Warn("Failed to issue tracking token")
 				continue
 			}
+			ad.ClickURL = ClickPath(ad.Token)
 			ads = append(ads, ad)
 		}
+		if len(ads) > 1 {
+			i := chooseIndex(ads, strategy)
+			ads[0], ads[i] = ads[i], ads[0]
+		}
 
 		duration := time.Since(start).Seconds()
-		adsQueryDuration.Observe(duration)
+		metrics.adsQueryDuration.Observe(duration)
 
 		logger.WithField("count", len(ads)).Info("Fetched ads successfully")
-		adsRequestCounter.WithLabelValues("200").Inc()
+		metrics.adsRequestCounter.WithLabelValues("200").Inc()
 		c.JSON(http.StatusOK, ads)
 	}
 }
@@ -1,34 +1,41 @@
 package ads
 
 import (
-	"sync"
-
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
-	adsRequestCounter = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "ads_requests_total",
-			Help: "Total number of GET /ads requests",
-		},
-		[]string{"status"},
-	)
-
-	adsQueryDuration = prometheus.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "ads_db_query_duration_seconds",
-			Help:    "Duration of ads DB query in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-	)
+// Metrics report the requests served by GetAdHandler.
+type Metrics struct {
+	adsRequestCounter *prometheus.CounterVec
+	adsQueryDuration  prometheus.Histogram
+}
 
-	registerOnce sync.Once
-)
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		adsRequestCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ads_requests_total",
+				Help: "Total number of GET /ads requests",
+			},
+			[]string{"status"},
+		),
+		adsQueryDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "ads_db_query_duration_seconds",
+				Help:    "Duration of ads DB query in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+	}
+}
 
-// InitAdMetrics registers ads-related Prometheus metrics (safe to call multiple times).
-func InitAdMetrics() {
-	registerOnce.Do(func() {
-		prometheus.MustRegister(adsRequestCounter, adsQueryDuration)
-	})
+// Register registers ad serving metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.adsRequestCounter, m.adsQueryDuration} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,7 +1,32 @@
 package ads
 
+import "github.com/Divyanth2468/video-ad-tracker/internal/signing"
+
 type Ad struct {
-	ID        string `json:"id"`
-	VideoURL  string `json:"video_url"`
-	TargetURL string `json:"target_url"`
+	ID         string `json:"id"`
+	CampaignID string `json:"campaign_id"`
+	VideoURL   string `json:"video_url"`
+	TargetURL  string `json:"target_url"`
+	Token      string `json:"token,omitempty"`
+	ClickURL   string `json:"click_url,omitempty"`
+
+	// Set when the ad is served as the viewer's arm of a running experiment.
+	ExperimentID string `json:"experiment_id,omitempty"`
+	Arm          string `json:"arm,omitempty"`
+}
+
+// Claims are the tracking token claims for serving ad to viewerID.
+func (ad Ad) Claims(viewerID string) signing.Token {
+	return signing.Token{
+		AdID:         ad.ID,
+		ViewerID:     viewerID,
+		ExperimentID: ad.ExperimentID,
+		Arm:          ad.Arm,
+	}
+}
+
+// ClickPath is the redirect path that records a click before sending the
+// viewer to TargetURL. token is the signed tracking token issued with the ad.
+func ClickPath(token string) string {
+	return "/c/" + token
 }
@@ -0,0 +1,81 @@
+package ads
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/experiments"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// Strategy picks which of the candidate ads to serve. adIDs is never empty.
+type Strategy interface {
+	Choose(adIDs []string) string
+}
+
+// RandomStrategy serves every candidate with equal probability.
+type RandomStrategy struct{}
+
+func (RandomStrategy) Choose(adIDs []string) string {
+	return adIDs[rand.Intn(len(adIDs))]
+}
+
+// eligible drops ads belonging to experiment arms the viewer is not in and
+// tags the ones that are the viewer's arm.
+func eligible(all []Ad, running []experiments.Experiment, viewerID string) []Ad {
+	out := all[:0]
+	for _, ad := range all {
+		if assignArm(running, &ad, viewerID) == ad.ID {
+			out = append(out, ad)
+		}
+	}
+	return out
+}
+
+// chooseIndex returns the position in candidates of the ad strategy picks.
+func chooseIndex(candidates []Ad, strategy Strategy) int {
+	ids := make([]string, len(candidates))
+	for i, ad := range candidates {
+		ids[i] = ad.ID
+	}
+	chosen := strategy.Choose(ids)
+	for i, id := range ids {
+		if id == chosen {
+			return i
+		}
+	}
+	return 0
+}
+
+// SelectAdForViewer returns the requested ad, or picks one with strategy
+// among the ads eligible for the viewer under any running experiment. An
+// explicitly requested ad is always served, tagged only if it is the
+// viewer's arm. It returns pgx.ErrNoRows when there is nothing to serve.
+func SelectAdForViewer(ctx context.Context, db *pgxpool.Pool, adID, viewerID string, strategy Strategy, logger logrus.FieldLogger) (Ad, error) {
+	if adID != "" && uuid.Validate(adID) != nil {
+		// Not an ad ID Postgres would accept, so no ad has it.
+		return Ad{}, pgx.ErrNoRows
+	}
+	running := runningExperiments(ctx, db, logger)
+	if adID != "" {
+		ad, err := GetAdByID(ctx, db, adID)
+		if err != nil {
+			return ad, err
+		}
+		assignArm(running, &ad, viewerID)
+		return ad, nil
+	}
+
+	all, err := ListAds(ctx, db)
+	if err != nil {
+		return Ad{}, err
+	}
+	candidates := eligible(all, running, viewerID)
+	if len(candidates) == 0 {
+		return Ad{}, pgx.ErrNoRows
+	}
+	return candidates[chooseIndex(candidates, strategy)], nil
+}
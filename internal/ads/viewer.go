@@ -0,0 +1,30 @@
+package ads
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ViewerCookie holds the first-party viewer ID issued by the ad-serving endpoints.
+const ViewerCookie = "vid"
+
+const viewerCookieMaxAge = 365 * 24 * 60 * 60
+
+// ViewerID identifies the viewer of a request: the viewerId query parameter
+// (for players that manage their own IDs), the vid cookie, or a newly issued
+// vid cookie.
+func ViewerID(c *gin.Context) string {
+	if id := c.Query("viewerId"); id != "" {
+		return id
+	}
+	if id, err := c.Cookie(ViewerCookie); err == nil && id != "" {
+		return id
+	}
+
+	id := uuid.New().String()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ViewerCookie, id, viewerCookieMaxAge, "/", "", false, true)
+	return id
+}
@@ -1,13 +1,17 @@
 package analytics
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 )
 
+var ctx = context.Background()
+
 func newTestRedisAnalytics(t *testing.T) (*RedisAnalytics, *miniredis.Miniredis) {
 	s, err := miniredis.Run()
 	if err != nil {
@@ -18,14 +22,14 @@ func newTestRedisAnalytics(t *testing.T) (*RedisAnalytics, *miniredis.Miniredis)
 		Addr: s.Addr(),
 	})
 
-	return &RedisAnalytics{Client: rdb}, s
+	return NewRedisAnalyticsFromClient(rdb, logs.Discard()), s
 }
 
 func TestIncrementTotal(t *testing.T) {
 	ra, s := newTestRedisAnalytics(t)
 	defer s.Close()
 
-	err := ra.IncrementTotal("test-ad")
+	_, err := ra.Apply(ctx, event().IncrementTotal("test-ad"))
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -44,7 +48,7 @@ func TestAddUnique(t *testing.T) {
 	ra, s := newTestRedisAnalytics(t)
 	defer s.Close()
 
-	err := ra.AddUnique("test-ad", "192.168.1.1")
+	_, err := ra.Apply(ctx, event().AddUnique("test-ad", "192.168.1.1"))
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -56,7 +60,7 @@ func TestIncrementHourly(t *testing.T) {
 	defer s.Close()
 
 	now := time.Date(2025, 7, 2, 18, 0, 0, 0, time.UTC)
-	err := ra.IncrementHourly("test-ad", now)
+	_, err := ra.Apply(ctx, event().IncrementHourly("test-ad", now))
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -71,7 +75,7 @@ func TestIncrementImpression(t *testing.T) {
 	ra, s := newTestRedisAnalytics(t)
 	defer s.Close()
 
-	err := ra.IncrementImpression("test-ad")
+	_, err := ra.Apply(ctx, event().IncrementImpression("test-ad"))
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -92,7 +96,7 @@ func TestGetTotalImpressions(t *testing.T) {
 
 	s.Set("ad:impressions:total:test-ad", "5")
 
-	val, err := ra.GetTotalImpressions("test-ad")
+	val, err := ra.GetTotalImpressions(ctx, "test-ad")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -112,7 +116,7 @@ func TestGetAnalytics(t *testing.T) {
 	s.HSet("ad:clicks:hourly:test-ad:20250702", "18", "5")
 
 	// Run test
-	result, err := ra.GetAnalytics("test-ad", "1h")
+	result, err := ra.GetAnalytics(ctx, "test-ad", "1h")
 	if err != nil {
 		t.Fatalf("Error getting analytics: %v", err)
 	}
@@ -127,3 +131,119 @@ func TestGetAnalytics(t *testing.T) {
 		t.Errorf("Expected CTR=0.5, got %v", result["ctr"])
 	}
 }
+
+func TestGetAnalytics_VideoRates(t *testing.T) {
+	ra, s := newTestRedisAnalytics(t)
+	defer s.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := ra.Apply(ctx, event().IncrementVideoEvent("test-ad", "start")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	s.Set("ad:video:complete:test-ad", "3")
+	s.Set("ad:video:skip:test-ad", "1")
+
+	result, err := ra.GetAnalytics(ctx, "test-ad", "1h")
+	if err != nil {
+		t.Fatalf("Error getting analytics: %v", err)
+	}
+
+	videoEvents := result["videoEvents"].(map[string]int)
+	if videoEvents["start"] != 4 || videoEvents["midpoint"] != 0 {
+		t.Errorf("Unexpected video event counts: %v", videoEvents)
+	}
+	if result["completionRate"].(float64) != 0.75 {
+		t.Errorf("Expected completionRate=0.75, got %v", result["completionRate"])
+	}
+	if result["skipRate"].(float64) != 0.25 {
+		t.Errorf("Expected skipRate=0.25, got %v", result["skipRate"])
+	}
+}
+
+func TestGetAnalytics_Conversions(t *testing.T) {
+	ra, s := newTestRedisAnalytics(t)
+	defer s.Close()
+
+	s.Set("ad:clicks:total:test-ad", "8")
+	for _, value := range []float64{20, 30} {
+		if _, err := ra.Apply(ctx, event().IncrementConversion("test-ad", value)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := ra.Apply(ctx, event().AddSpend("test-ad", 3.5)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := ra.Apply(ctx, event().AddSpend("test-ad", 0.5)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := ra.GetAnalytics(ctx, "test-ad", "1h")
+	if err != nil {
+		t.Fatalf("Error getting analytics: %v", err)
+	}
+
+	if result["conversions"].(int) != 2 {
+		t.Errorf("Expected conversions=2, got %v", result["conversions"])
+	}
+	if result["conversionValue"].(float64) != 50 {
+		t.Errorf("Expected conversionValue=50, got %v", result["conversionValue"])
+	}
+	if result["conversionRate"].(float64) != 0.25 {
+		t.Errorf("Expected conversionRate=0.25, got %v", result["conversionRate"])
+	}
+	if result["costPerConversion"].(float64) != 2 {
+		t.Errorf("Expected costPerConversion=2, got %v", result["costPerConversion"])
+	}
+}
+
+func TestGetArmCounts(t *testing.T) {
+	ra, s := newTestRedisAnalytics(t)
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := ra.Apply(ctx, event().IncrementArmImpression("exp-1", "control")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := ra.Apply(ctx, event().IncrementArmClick("exp-1", "control")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	impressions, clicks, err := ra.GetArmCounts(ctx, "exp-1", "control")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if impressions != 3 || clicks != 1 {
+		t.Errorf("Expected 3 impressions and 1 click, got %d and %d", impressions, clicks)
+	}
+
+	impressions, clicks, err = ra.GetArmCounts(ctx, "exp-1", "variant")
+	if err != nil || impressions != 0 || clicks != 0 {
+		t.Errorf("Expected empty arm, got %d, %d, %v", impressions, clicks, err)
+	}
+}
+
+func TestApply_ExpiresAppliedMarker(t *testing.T) {
+	ra, s := newTestRedisAnalytics(t)
+	defer s.Close()
+
+	batch := NewBatch("video:v1", time.Minute).IncrementVideoEvent("test-ad", "start")
+	for i := 0; i < 2; i++ {
+		if _, err := ra.Apply(ctx, batch); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if val, _ := s.Get("ad:video:start:test-ad"); val != "1" {
+		t.Errorf("Expected the redelivered event to be counted once, got %s", val)
+	}
+	if ttl := s.TTL("analytics:applied:video:v1"); ttl != time.Minute {
+		t.Errorf("Expected the applied marker to expire in 1m, got %v", ttl)
+	}
+
+	s.FastForward(time.Minute)
+	applied, err := ra.Apply(ctx, batch)
+	if err != nil || !applied {
+		t.Errorf("Expected the event to be applied again once its marker expired, got %v, %v", applied, err)
+	}
+}
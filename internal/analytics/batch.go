@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"time"
+)
+
+// Operations a Batch is made of, named after the Redis commands that apply
+// them.
+const (
+	opIncrBy      = "incrby"
+	opIncrByFloat = "incrbyfloat"
+	opPFAdd       = "pfadd"
+	opHIncrBy     = "hincrby"
+)
+
+// op is one counter update of the Redis key key.
+type op struct {
+	name   string
+	key    string
+	field  string // hash field or HyperLogLog member
+	amount float64
+}
+
+// Batch collects the counter updates of one event so that Apply makes all
+// of them, exactly once per event ID, however often the event is delivered.
+type Batch struct {
+	eventID string
+	ttl     time.Duration
+	ops     []op
+}
+
+// NewBatch starts the updates of the event eventID. The event is remembered
+// as applied for ttl, which must outlast any redelivery of it.
+func NewBatch(eventID string, ttl time.Duration) *Batch {
+	return &Batch{eventID: eventID, ttl: ttl}
+}
+
+// Len returns the number of updates in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *Batch) add(name, key, field string, amount float64) *Batch {
+	b.ops = append(b.ops, op{name: name, key: key, field: field, amount: amount})
+	return b
+}
+
+// IncrementTotal counts a valid click.
+func (b *Batch) IncrementTotal(adId string) *Batch {
+	return b.add(opIncrBy, totalClicksKey(adId), "", 1)
+}
+
+// IncrementInvalid counts a click rejected by fraud scoring.
+func (b *Batch) IncrementInvalid(adId string) *Batch {
+	return b.add(opIncrBy, invalidClicksKey(adId), "", 1)
+}
+
+// AddUnique adds a clicking IP.
+func (b *Batch) AddUnique(adId, ip string) *Batch {
+	return b.add(opPFAdd, uniqueClicksKey(adId), ip, 0)
+}
+
+// IncrementHourly counts a click in the hour of t.
+func (b *Batch) IncrementHourly(adId string, t time.Time) *Batch {
+	return b.add(opHIncrBy, hourlyClicksKey(adId, t), hourField(t), 1)
+}
+
+// IncrementImpression counts an impression.
+func (b *Batch) IncrementImpression(adId string) *Batch {
+	return b.add(opIncrBy, impressionsKey(adId), "", 1)
+}
+
+// IncrementVideoEvent counts a video playback or interaction event.
+func (b *Batch) IncrementVideoEvent(adId, event string) *Batch {
+	return b.add(opIncrBy, videoEventKey(adId, event), "", 1)
+}
+
+// IncrementConversion counts an attributed conversion and its value.
+func (b *Batch) IncrementConversion(adId string, value float64) *Batch {
+	b.add(opIncrBy, conversionsKey(adId), "", 1)
+	return b.add(opIncrByFloat, conversionValueKey(adId), "", value)
+}
+
+// AddSpend adds to the ad's spend.
+func (b *Batch) AddSpend(adId string, amount float64) *Batch {
+	if amount == 0 {
+		return b
+	}
+	return b.add(opIncrByFloat, spendKey(adId), "", amount)
+}
+
+// IncrementArmImpression counts an impression for an experiment arm.
+func (b *Batch) IncrementArmImpression(experimentId, arm string) *Batch {
+	return b.add(opIncrBy, armKey(experimentId, arm, armImpressions), "", 1)
+}
+
+// IncrementArmClick counts a valid click for an experiment arm.
+func (b *Batch) IncrementArmClick(experimentId, arm string) *Batch {
+	return b.add(opIncrBy, armKey(experimentId, arm, armClicks), "", 1)
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import "time"
+
+// Redis keys of the counters. Batches write them and the stores read them;
+// MemoryAnalytics keys its maps the same way.
+
+func totalClicksKey(adId string) string {
+	return "ad:clicks:total:" + adId
+}
+
+func invalidClicksKey(adId string) string {
+	return "ad:clicks:invalid:" + adId
+}
+
+func uniqueClicksKey(adId string) string {
+	return "ads:clicks:unique:" + adId
+}
+
+// hourlyClicksKey is the hash of clicks on the day of t, with a field per
+// hour.
+func hourlyClicksKey(adId string, t time.Time) string {
+	return "ad:clicks:hourly:" + adId + ":" + t.Format("20060102")
+}
+
+func hourField(t time.Time) string {
+	return t.Format("15")
+}
+
+func impressionsKey(adId string) string {
+	return "ad:impressions:total:" + adId
+}
+
+func videoEventKey(adId, event string) string {
+	return "ad:video:" + event + ":" + adId
+}
+
+func conversionsKey(adId string) string {
+	return "ad:conversions:total:" + adId
+}
+
+func conversionValueKey(adId string) string {
+	return "ad:conversions:value:" + adId
+}
+
+func spendKey(adId string) string {
+	return "ad:spend:" + adId
+}
+
+// Experiment arm metrics
+const (
+	armImpressions = "impressions"
+	armClicks      = "clicks"
+)
+
+func armKey(experimentId, arm, metric string) string {
+	return "exp:" + experimentId + ":" + arm + ":" + metric
+}
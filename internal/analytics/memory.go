@@ -0,0 +1,146 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryAnalytics is an in-process AnalyticsStore. Counters live only as long
+// as the process; unique clicks are counted exactly rather than estimated.
+type MemoryAnalytics struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	floats  map[string]float64
+	unique  map[string]map[string]struct{}
+	hourly  map[string]map[string]int
+	applied map[string]time.Time // event ID to expiry
+	// sweepAt is the size of applied at which expired events are next
+	// deleted from it.
+	sweepAt int
+}
+
+// minSweep is the smallest size of MemoryAnalytics.applied that is swept.
+const minSweep = 64
+
+func NewMemoryAnalytics() *MemoryAnalytics {
+	return &MemoryAnalytics{
+		counts:  make(map[string]int),
+		floats:  make(map[string]float64),
+		unique:  make(map[string]map[string]struct{}),
+		hourly:  make(map[string]map[string]int),
+		applied: make(map[string]time.Time),
+		sweepAt: minSweep,
+	}
+}
+
+// Counters are keyed like their Redis counterparts.
+
+func (m *MemoryAnalytics) get(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[key]
+}
+
+func (m *MemoryAnalytics) GetTotalClicks(ctx context.Context, adId string) (int, error) {
+	return m.get(totalClicksKey(adId)), nil
+}
+
+func (m *MemoryAnalytics) GetInvalidClicks(ctx context.Context, adId string) (int, error) {
+	return m.get(invalidClicksKey(adId)), nil
+}
+
+func (m *MemoryAnalytics) GetUniqueClicks(ctx context.Context, adId string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.unique[uniqueClicksKey(adId)])), nil
+}
+
+func (m *MemoryAnalytics) GetHourlyClicks(ctx context.Context, adId string, day time.Time) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hours := make(map[string]int)
+	for hour, count := range m.hourly[hourlyClicksKey(adId, day)] {
+		hours[hour] = count
+	}
+	return hours, nil
+}
+
+func (m *MemoryAnalytics) GetTotalImpressions(ctx context.Context, adId string) (int, error) {
+	return m.get(impressionsKey(adId)), nil
+}
+
+func (m *MemoryAnalytics) GetVideoEventCounts(ctx context.Context, adId string, types []string) (map[string]int, error) {
+	counts := make(map[string]int, len(types))
+	for _, event := range types {
+		counts[event] = m.get(videoEventKey(adId, event))
+	}
+	return counts, nil
+}
+
+func (m *MemoryAnalytics) GetConversions(ctx context.Context, adId string) (int, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[conversionsKey(adId)], m.floats[conversionValueKey(adId)], nil
+}
+
+func (m *MemoryAnalytics) GetSpend(ctx context.Context, adId string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.floats[spendKey(adId)], nil
+}
+
+func (m *MemoryAnalytics) GetArmCounts(ctx context.Context, experimentId, arm string) (int, int, error) {
+	return m.get(armKey(experimentId, arm, armImpressions)), m.get(armKey(experimentId, arm, armClicks)), nil
+}
+
+func (m *MemoryAnalytics) GetAnalytics(ctx context.Context, adId, timeframe string) (map[string]interface{}, error) {
+	return aggregate(ctx, m, adId, timeframe)
+}
+
+// Apply makes every update in b under one lock, unless b's event was
+// applied within its TTL.
+func (m *MemoryAnalytics) Apply(ctx context.Context, b *Batch) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if expiry, ok := m.applied[b.eventID]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	if len(m.applied) >= m.sweepAt {
+		m.sweep(now)
+	}
+	m.applied[b.eventID] = now.Add(b.ttl)
+
+	for _, op := range b.ops {
+		switch op.name {
+		case opIncrBy:
+			m.counts[op.key] += int(op.amount)
+		case opIncrByFloat:
+			m.floats[op.key] += op.amount
+		case opPFAdd:
+			if m.unique[op.key] == nil {
+				m.unique[op.key] = make(map[string]struct{})
+			}
+			m.unique[op.key][op.field] = struct{}{}
+		case opHIncrBy:
+			if m.hourly[op.key] == nil {
+				m.hourly[op.key] = make(map[string]int)
+			}
+			m.hourly[op.key][op.field] += int(op.amount)
+		}
+	}
+	return true, nil
+}
+
+// sweep deletes the events whose applied marker expired. It runs whenever
+// applied has doubled since the last sweep, so the map stays within twice
+// the events applied within their TTL at a constant cost per Apply.
+func (m *MemoryAnalytics) sweep(now time.Time) {
+	for id, expiry := range m.applied {
+		if !now.Before(expiry) {
+			delete(m.applied, id)
+		}
+	}
+	m.sweepAt = max(2*len(m.applied), minSweep)
+}
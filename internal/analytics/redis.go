@@ -2,170 +2,206 @@ package analytics
 
 import (
 	"context"
-	"fmt"
+	"math"
 	"strconv"
 	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
-var (
-	ctx    = context.Background()
-	logger = logs.Logger
-)
-
+// RedisAnalytics is the production AnalyticsStore. Failed Redis calls are
+// logged to its logger as well as returned.
 type RedisAnalytics struct {
 	Client *redis.Client
+	logger logrus.FieldLogger
 }
 
-func NewRedisAnalyticsFromClient(rdb *redis.Client) *RedisAnalytics {
-	return &RedisAnalytics{Client: rdb}
+func NewRedisAnalyticsFromClient(rdb *redis.Client, logger logrus.FieldLogger) *RedisAnalytics {
+	return &RedisAnalytics{Client: rdb, logger: logger}
 }
 
-func NewRedisAnalytics(addr, password string, db int) *RedisAnalytics {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-	})
-
-	logger.WithFields(map[string]interface{}{
-		"redisAddr": addr,
-		"db":        db,
-	}).Info("Initialized Redis client")
-
-	return &RedisAnalytics{Client: rdb}
-}
+// appliedKeyPrefix marks the events whose counter updates were applied.
+const appliedKeyPrefix = "analytics:applied:"
+
+// applyScript makes a Batch's updates in one step. KEYS[1] marks the event
+// applied for ARGV[1] seconds; if it is already set nothing is updated.
+// Each further key is updated by the operation, field and amount at
+// ARGV[3j-1], ARGV[3j] and ARGV[3j+1].
+var applyScript = redis.NewScript(`
+if not redis.call("SET", KEYS[1], "1", "NX", "EX", ARGV[1]) then
+	return 0
+end
+for j = 1, #KEYS - 1 do
+	local key, op, field, amount = KEYS[j + 1], ARGV[3 * j - 1], ARGV[3 * j], ARGV[3 * j + 1]
+	if op == "incrby" then
+		redis.call("INCRBY", key, amount)
+	elseif op == "incrbyfloat" then
+		redis.call("INCRBYFLOAT", key, amount)
+	elseif op == "pfadd" then
+		redis.call("PFADD", key, field)
+	elseif op == "hincrby" then
+		redis.call("HINCRBY", key, field, amount)
+	end
+end
+return 1`)
+
+// Apply makes every update in b atomically, unless b's event was already
+// applied, and reports whether it did.
+func (ra *RedisAnalytics) Apply(ctx context.Context, b *Batch) (bool, error) {
+	keys := make([]string, 0, len(b.ops)+1)
+	args := make([]interface{}, 0, 3*len(b.ops)+1)
+	keys = append(keys, appliedKeyPrefix+b.eventID)
+	args = append(args, int64(math.Ceil(b.ttl.Seconds())))
+	for _, op := range b.ops {
+		keys = append(keys, op.key)
+		args = append(args, op.name, op.field, strconv.FormatFloat(op.amount, 'f', -1, 64))
+	}
 
-// Increment total clicks
-func (ra *RedisAnalytics) IncrementTotal(adId string) error {
-	key := "ad:clicks:total:" + adId
-	err := ra.Client.Incr(ctx, key).Err()
+	applied, err := applyScript.Run(ctx, ra.Client, keys, args...).Int()
 	if err != nil {
-		logger.WithField("key", key).WithError(err).Error("Failed to increment total clicks")
+		ra.logger.WithField("eventID", b.eventID).WithError(err).Error("Failed to apply analytics updates")
+		return false, err
 	}
-	return err
+	return applied == 1, nil
 }
 
-// Add unique IP to HyperLogLog
-func (ra *RedisAnalytics) AddUnique(adId, ip string) error {
-	key := "ads:clicks:unique:" + adId
-	err := ra.Client.PFAdd(ctx, key, ip).Err()
+// GetArmCounts returns an experiment arm's impressions and clicks
+func (ra *RedisAnalytics) GetArmCounts(ctx context.Context, experimentId, arm string) (impressions, clicks int, err error) {
+	vals, err := ra.Client.MGet(ctx, armKey(experimentId, arm, armImpressions), armKey(experimentId, arm, armClicks)).Result()
 	if err != nil {
-		logger.WithFields(map[string]interface{}{"key": key, "ip": ip}).WithError(err).Error("Failed to add unique click")
+		ra.logger.WithField("experimentId", experimentId).WithError(err).Error("Failed to get experiment arm counts")
+		return 0, 0, err
+	}
+	counts := make([]int, len(vals))
+	for i, v := range vals {
+		if s, ok := v.(string); ok {
+			counts[i], _ = strconv.Atoi(s)
+		}
 	}
-	return err
+	return counts[0], counts[1], nil
 }
 
-// Increment hourly click count
-func (ra *RedisAnalytics) IncrementHourly(adId string, t time.Time) error {
-	key := "ad:clicks:hourly:" + adId + ":" + t.Format("20060102")
-	hour := t.Format("15")
-	err := ra.Client.HIncrBy(ctx, key, hour, 1).Err()
+// GetVideoEventCounts returns the count of each requested video event type
+func (ra *RedisAnalytics) GetVideoEventCounts(ctx context.Context, adId string, types []string) (map[string]int, error) {
+	counts := make(map[string]int, len(types))
+	if len(types) == 0 {
+		return counts, nil
+	}
+
+	keys := make([]string, len(types))
+	for i, event := range types {
+		keys[i] = videoEventKey(adId, event)
+	}
+	vals, err := ra.Client.MGet(ctx, keys...).Result()
 	if err != nil {
-		logger.WithFields(map[string]interface{}{"key": key, "hour": hour}).WithError(err).Error("Failed to increment hourly clicks")
+		ra.logger.WithField("adId", adId).WithError(err).Error("Failed to get video events")
+		return nil, err
 	}
-	return err
+	for i, v := range vals {
+		count := 0
+		if s, ok := v.(string); ok {
+			count, _ = strconv.Atoi(s)
+		}
+		counts[types[i]] = count
+	}
+	return counts, nil
 }
 
-// Increment impression count
-func (ra *RedisAnalytics) IncrementImpression(adId string) error {
-	key := "ad:impressions:total:" + adId
-	err := ra.Client.Incr(ctx, key).Err()
-	if err != nil {
-		logger.WithField("key", key).WithError(err).Error("Failed to increment impressions")
+// Get total valid clicks
+func (ra *RedisAnalytics) GetTotalClicks(ctx context.Context, adId string) (int, error) {
+	key := totalClicksKey(adId)
+	clicks, err := ra.Client.Get(ctx, key).Int()
+	if err != nil && err != redis.Nil {
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get clicks")
+		return 0, err
 	}
-	return err
+	return clicks, nil
 }
 
 // Get total impressions
-func (ra *RedisAnalytics) GetTotalImpressions(adId string) (int, error) {
-	key := "ad:impressions:total:" + adId
+func (ra *RedisAnalytics) GetTotalImpressions(ctx context.Context, adId string) (int, error) {
+	key := impressionsKey(adId)
 	impressions, err := ra.Client.Get(ctx, key).Int()
 	if err != nil && err != redis.Nil {
-		logger.WithField("key", key).WithError(err).Error("Failed to get impressions")
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get impressions")
 		return 0, err
 	}
 	return impressions, nil
 }
 
-// GetAnalytics returns aggregated metrics
-func (ra *RedisAnalytics) GetAnalytics(adId, timeframe string) (map[string]interface{}, error) {
-	result := make(map[string]interface{})
+// Get clicks rejected by fraud scoring
+func (ra *RedisAnalytics) GetInvalidClicks(ctx context.Context, adId string) (int, error) {
+	key := invalidClicksKey(adId)
+	invalid, err := ra.Client.Get(ctx, key).Int()
+	if err != nil && err != redis.Nil {
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get invalid clicks")
+		return 0, err
+	}
+	return invalid, nil
+}
 
-	// Total clicks
-	totalClicks, err := ra.Client.Get(ctx, "ad:clicks:total:"+adId).Int()
+// Get the approximate number of unique clicking IPs
+func (ra *RedisAnalytics) GetUniqueClicks(ctx context.Context, adId string) (int64, error) {
+	key := uniqueClicksKey(adId)
+	unique, err := ra.Client.PFCount(ctx, key).Result()
 	if err != nil && err != redis.Nil {
-		logger.WithError(err).Error("Failed to get total clicks")
-		return nil, err
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get unique clicks")
+		return 0, err
 	}
-	result["totalClicks"] = totalClicks
+	return unique, nil
+}
 
-	// Unique clicks
-	uniqueClicks, err := ra.Client.PFCount(ctx, "ads:clicks:unique:"+adId).Result()
+// Get clicks per hour on the given day
+func (ra *RedisAnalytics) GetHourlyClicks(ctx context.Context, adId string, day time.Time) (map[string]int, error) {
+	key := hourlyClicksKey(adId, day)
+	hmap, err := ra.Client.HGetAll(ctx, key).Result()
 	if err != nil && err != redis.Nil {
-		logger.WithError(err).Error("Failed to get unique clicks")
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get hourly clicks")
 		return nil, err
 	}
-	result["uniqueClicks"] = uniqueClicks
-
-	// Hourly clicks
-	hourlyClicks := make(map[string]int)
-	now := time.Now()
-	var days int
-
-	switch timeframe {
-	case "1h", "24h":
-		days = 1
-	case "7d":
-		days = 7
-	default:
-		days = 30
-	}
-
-	for i := 0; i < days; i++ {
-		day := now.AddDate(0, 0, -i).Format("20060102")
-		key := fmt.Sprintf("ad:clicks:hourly:%s:%s", adId, day)
-		hmap, err := ra.Client.HGetAll(ctx, key).Result()
-		if err != nil && err != redis.Nil {
-			logger.WithField("key", key).WithError(err).Error("Failed to get hourly clicks")
-			return nil, err
-		}
-		for hour, val := range hmap {
-			if count, err := strconv.Atoi(val); err == nil {
-				hourlyClicks[hour] += count
-			}
+	hours := make(map[string]int, len(hmap))
+	for hour, val := range hmap {
+		if count, err := strconv.Atoi(val); err == nil {
+			hours[hour] = count
 		}
 	}
-	result["hourlyClicks"] = hourlyClicks
+	return hours, nil
+}
 
-	// Get impressions
-	impressions, err := ra.GetTotalImpressions(adId)
-	if err != nil {
-		return nil, err
+// Get attributed conversions and their total value
+func (ra *RedisAnalytics) GetConversions(ctx context.Context, adId string) (int, float64, error) {
+	count, err := ra.Client.Get(ctx, conversionsKey(adId)).Int()
+	if err != nil && err != redis.Nil {
+		ra.logger.WithError(err).Error("Failed to get conversions")
+		return 0, 0, err
 	}
-	result["impressions"] = impressions
-
-	var ctr float64
-	// Calculate CTR
-	if impressions > 0 {
-		ctr = float64(totalClicks) / float64(impressions)
-	} else {
-		ctr = 0.0
+	value, err := ra.getFloat(ctx, conversionValueKey(adId))
+	if err != nil {
+		return 0, 0, err
 	}
+	return count, value, nil
+}
 
-	result["ctr"] = ctr
+// Get the ad's accrued spend
+func (ra *RedisAnalytics) GetSpend(ctx context.Context, adId string) (float64, error) {
+	return ra.getFloat(ctx, spendKey(adId))
+}
 
-	logger.WithFields(map[string]interface{}{
-		"adId":        adId,
-		"timeframe":   timeframe,
-		"totalClicks": totalClicks,
-		"unique":      uniqueClicks,
-		"ctr":         ctr,
-	}).Info("Fetched analytics")
+// GetAnalytics returns aggregated metrics
+func (ra *RedisAnalytics) GetAnalytics(ctx context.Context, adId, timeframe string) (map[string]interface{}, error) {
+	return aggregate(ctx, ra, adId, timeframe)
+}
 
-	return result, nil
+func (ra *RedisAnalytics) getFloat(ctx context.Context, key string) (float64, error) {
+	v, err := ra.Client.Get(ctx, key).Float64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		ra.logger.WithField("key", key).WithError(err).Error("Failed to get value")
+	}
+	return v, err
 }
 
 func (ra *RedisAnalytics) CloseRedis() error {
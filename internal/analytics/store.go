@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/events"
+)
+
+// AnalyticsStore holds the real-time counters the workers update through
+// Batches and the analytics endpoints read. RedisAnalytics is the production store;
+// MemoryAnalytics has the same semantics for tests and local runs.
+type AnalyticsStore interface {
+	// Apply makes all of a Batch's updates together, once per event: it
+	// reports false, changing nothing, if the batch's event was already
+	// applied.
+	Apply(ctx context.Context, b *Batch) (bool, error)
+
+	GetTotalClicks(ctx context.Context, adId string) (int, error)
+	GetInvalidClicks(ctx context.Context, adId string) (int, error)
+	GetUniqueClicks(ctx context.Context, adId string) (int64, error)
+	// GetHourlyClicks returns the clicks per hour ("00".."23") on day.
+	GetHourlyClicks(ctx context.Context, adId string, day time.Time) (map[string]int, error)
+	GetTotalImpressions(ctx context.Context, adId string) (int, error)
+	GetVideoEventCounts(ctx context.Context, adId string, types []string) (map[string]int, error)
+	GetConversions(ctx context.Context, adId string) (count int, value float64, err error)
+	GetSpend(ctx context.Context, adId string) (float64, error)
+	GetArmCounts(ctx context.Context, experimentId, arm string) (impressions, clicks int, err error)
+
+	// GetAnalytics returns the aggregated metrics served by /ads/analytics.
+	GetAnalytics(ctx context.Context, adId, timeframe string) (map[string]interface{}, error)
+}
+
+var (
+	_ AnalyticsStore = (*RedisAnalytics)(nil)
+	_ AnalyticsStore = (*MemoryAnalytics)(nil)
+)
+
+// aggregate builds the /ads/analytics response from a store's counters, so
+// every store reports the same derived metrics.
+func aggregate(ctx context.Context, store AnalyticsStore, adId, timeframe string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	// Total clicks
+	totalClicks, err := store.GetTotalClicks(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	result["totalClicks"] = totalClicks
+
+	// Clicks excluded from totals by fraud scoring
+	invalidClicks, err := store.GetInvalidClicks(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	result["invalidClicks"] = invalidClicks
+
+	// Unique clicks
+	uniqueClicks, err := store.GetUniqueClicks(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	result["uniqueClicks"] = uniqueClicks
+
+	// Hourly clicks
+	hourlyClicks := make(map[string]int)
+	now := time.Now()
+	var days int
+
+	switch timeframe {
+	case "1h", "24h":
+		days = 1
+	case "7d":
+		days = 7
+	default:
+		days = 30
+	}
+
+	for i := 0; i < days; i++ {
+		hours, err := store.GetHourlyClicks(ctx, adId, now.AddDate(0, 0, -i))
+		if err != nil {
+			return nil, err
+		}
+		for hour, count := range hours {
+			hourlyClicks[hour] += count
+		}
+	}
+	result["hourlyClicks"] = hourlyClicks
+
+	// Get impressions
+	impressions, err := store.GetTotalImpressions(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	result["impressions"] = impressions
+
+	// Calculate CTR
+	ctr := rate(totalClicks, impressions)
+	result["ctr"] = ctr
+
+	// Video events and the rates derived from them, relative to starts
+	videoEvents, err := store.GetVideoEventCounts(ctx, adId, events.VideoEventTypes)
+	if err != nil {
+		return nil, err
+	}
+	result["videoEvents"] = videoEvents
+	result["completionRate"] = rate(videoEvents[events.VideoComplete], videoEvents[events.VideoStart])
+	result["skipRate"] = rate(videoEvents[events.VideoSkip], videoEvents[events.VideoStart])
+
+	// Conversions, relative to valid clicks, and what each one cost
+	conversions, conversionValue, err := store.GetConversions(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	spend, err := store.GetSpend(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	result["conversions"] = conversions
+	result["conversionValue"] = conversionValue
+	result["conversionRate"] = rate(conversions, totalClicks)
+	result["spend"] = spend
+	result["costPerConversion"] = 0.0
+	if conversions > 0 {
+		result["costPerConversion"] = spend / float64(conversions)
+	}
+
+	return result, nil
+}
+
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0.0
+	}
+	return float64(count) / float64(total)
+}
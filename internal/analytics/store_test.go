@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stores returns a fresh instance of every AnalyticsStore; each conformance
+// test below runs against all of them.
+func stores(t *testing.T) map[string]AnalyticsStore {
+	s := miniredis.RunT(t)
+	return map[string]AnalyticsStore{
+		"redis":  NewRedisAnalyticsFromClient(redis.NewClient(&redis.Options{Addr: s.Addr()}), logs.Discard()),
+		"memory": NewMemoryAnalytics(),
+	}
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, store AnalyticsStore)) {
+	for name, store := range stores(t) {
+		store := store
+		t.Run(name, func(t *testing.T) { test(t, store) })
+	}
+}
+
+var eventSeq atomic.Int64
+
+// event starts the batch of a new event.
+func event() *Batch {
+	return NewBatch(fmt.Sprintf("test:%d", eventSeq.Add(1)), time.Hour)
+}
+
+func apply(t *testing.T, store AnalyticsStore, b *Batch) {
+	applied, err := store.Apply(ctx, b)
+	require.NoError(t, err)
+	require.True(t, applied)
+}
+
+func TestStore_Counters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store AnalyticsStore) {
+		apply(t, store, event().IncrementTotal("ad"))
+		apply(t, store, event().IncrementTotal("ad"))
+		apply(t, store, event().IncrementInvalid("ad"))
+		apply(t, store, event().IncrementImpression("ad"))
+
+		clicks, err := store.GetTotalClicks(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 2, clicks)
+		invalid, err := store.GetInvalidClicks(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 1, invalid)
+		impressions, err := store.GetTotalImpressions(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 1, impressions)
+
+		clicks, err = store.GetTotalClicks(ctx, "other")
+		require.NoError(t, err)
+		assert.Zero(t, clicks)
+	})
+}
+
+func TestStore_Unique(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store AnalyticsStore) {
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+			apply(t, store, event().AddUnique("ad", ip))
+		}
+		unique, err := store.GetUniqueClicks(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), unique)
+	})
+}
+
+func TestStore_Hourly(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store AnalyticsStore) {
+		day := time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC)
+		apply(t, store, event().IncrementHourly("ad", day.Add(18*time.Hour)))
+		apply(t, store, event().IncrementHourly("ad", day.Add(18*time.Hour+30*time.Minute)))
+		apply(t, store, event().IncrementHourly("ad", day.Add(9*time.Hour)))
+		apply(t, store, event().IncrementHourly("ad", day.Add(24*time.Hour)))
+
+		hours, err := store.GetHourlyClicks(ctx, "ad", day)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"18": 2, "09": 1}, hours)
+	})
+}
+
+func TestStore_VideoConversionsAndArms(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store AnalyticsStore) {
+		apply(t, store, event().IncrementVideoEvent("ad", "start"))
+		apply(t, store, event().IncrementConversion("ad", 12.5))
+		apply(t, store, event().IncrementConversion("ad", 7.5))
+		apply(t, store, event().AddSpend("ad", 1.25))
+		apply(t, store, event().IncrementArmImpression("exp", "control"))
+		apply(t, store, event().IncrementArmClick("exp", "control"))
+
+		video, err := store.GetVideoEventCounts(ctx, "ad", []string{"start", "complete"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"start": 1, "complete": 0}, video)
+
+		count, value, err := store.GetConversions(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, 20.0, value)
+
+		spend, err := store.GetSpend(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 1.25, spend)
+
+		impressions, clicks, err := store.GetArmCounts(ctx, "exp", "control")
+		require.NoError(t, err)
+		assert.Equal(t, 1, impressions)
+		assert.Equal(t, 1, clicks)
+	})
+}
+
+func TestStore_ApplyOncePerEvent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store AnalyticsStore) {
+		at := time.Date(2025, 7, 2, 18, 30, 0, 0, time.UTC)
+		click := func(id string) *Batch {
+			return NewBatch("click:"+id, time.Hour).
+				IncrementTotal("ad").
+				AddUnique("ad", "10.0.0.1").
+				IncrementHourly("ad", at).
+				IncrementArmClick("exp", "control").
+				AddSpend("ad", 0.5)
+		}
+
+		for i, id := range []string{"c1", "c1", "c2"} {
+			applied, err := store.Apply(ctx, click(id))
+			require.NoError(t, err)
+			assert.Equal(t, i != 1, applied, "redelivery of c1 is not applied")
+		}
+		applied, err := store.Apply(ctx, NewBatch("conversion:v1", time.Hour).IncrementConversion("ad", 20))
+		require.NoError(t, err)
+		assert.True(t, applied)
+
+		total, err := store.GetTotalClicks(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 2, total)
+		unique, err := store.GetUniqueClicks(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), unique)
+		hours, err := store.GetHourlyClicks(ctx, "ad", at)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"18": 2}, hours)
+		_, clicks, err := store.GetArmCounts(ctx, "exp", "control")
+		require.NoError(t, err)
+		assert.Equal(t, 2, clicks)
+		spend, err := store.GetSpend(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, spend)
+		count, value, err := store.GetConversions(ctx, "ad")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Equal(t, 20.0, value)
+	})
+}
+
+func TestMemory_SweepsExpiredEvents(t *testing.T) {
+	m := NewMemoryAnalytics()
+	for i := 0; i < 100; i++ {
+		apply(t, m, NewBatch(fmt.Sprintf("short:%d", i), time.Millisecond).IncrementTotal("ad"))
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 200; i++ {
+		apply(t, m, event().IncrementTotal("ad"))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := range m.applied {
+		assert.NotContains(t, id, "short:", "expired events are deleted")
+	}
+	assert.LessOrEqual(t, len(m.applied), 400)
+}
+
+// TestStore_SameAnalytics applies the same updates to every store and
+// expects identical /ads/analytics output.
+func TestStore_SameAnalytics(t *testing.T) {
+	now := time.Now()
+	results := make(map[string]map[string]interface{})
+	for name, store := range stores(t) {
+		for i := 0; i < 4; i++ {
+			apply(t, store, event().IncrementImpression("ad"))
+			apply(t, store, event().IncrementVideoEvent("ad", "start"))
+		}
+		apply(t, store, event().IncrementTotal("ad"))
+		apply(t, store, event().AddUnique("ad", "10.0.0.1"))
+		apply(t, store, event().IncrementHourly("ad", now))
+		apply(t, store, event().IncrementVideoEvent("ad", "complete"))
+		apply(t, store, event().IncrementConversion("ad", 10))
+		apply(t, store, event().AddSpend("ad", 2))
+
+		result, err := store.GetAnalytics(ctx, "ad", "24h")
+		require.NoError(t, err)
+		results[name] = result
+	}
+
+	assert.Equal(t, results["redis"], results["memory"])
+	assert.Equal(t, 0.25, results["memory"]["ctr"])
+	assert.Equal(t, 2.0, results["memory"]["costPerConversion"])
+}
@@ -0,0 +1,375 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/Divyanth2468/video-ad-tracker/internal/bandit"
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/fraud"
+	"github.com/Divyanth2468/video-ad-tracker/internal/ingest"
+	"github.com/Divyanth2468/video-ad-tracker/internal/leader"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/scheduler"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracing"
+	"github.com/Divyanth2468/video-ad-tracker/internal/worker"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// App owns the process-wide resources — database pool, Redis client,
+// logger, metrics registry and worker pool — and hands them to the
+// handlers and workers that need them.
+type App struct {
+	Logger    *logrus.Logger
+	DB        *pgxpool.Pool
+	Redis     *redis.Client
+	Analytics *analytics.RedisAnalytics
+	Queue     queue.Queue
+	Fallback  *clicks.Fallback
+	Registry  *prometheus.Registry
+	Signer    *signing.Signer
+	Detector  *fraud.Detector
+	Strategy  ads.Strategy
+	Workers   *worker.Pool
+	Ingest    *ingest.Gate
+	Elector   *leader.Elector
+	Scheduler *scheduler.Scheduler
+
+	// LoadConfig re-reads the configuration for Reload.
+	LoadConfig func() (*config.Config, error)
+
+	cfg      *config.Config
+	reloadMu sync.Mutex
+	thompson *bandit.Thompson
+	server   *http.Server
+	cancel   context.CancelFunc
+	draining atomic.Bool
+
+	tracer          trace.TracerProvider
+	shutdownTracing func(context.Context) error
+
+	metrics             componentMetrics
+	httpRequestsTotal   *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+}
+
+// componentMetrics are the metrics of the app's components, each registered
+// with the app's registry by registerMetrics.
+type componentMetrics struct {
+	ads       *ads.Metrics
+	signing   *signing.Metrics
+	fraud     *fraud.Metrics
+	bandit    *bandit.Metrics
+	worker    *worker.Metrics
+	ingest    *ingest.Metrics
+	leader    *leader.Metrics
+	scheduler *scheduler.Metrics
+}
+
+// New connects to Postgres and Redis and wires up the application. Nothing
+// runs until Start is called; Stop releases what New acquired.
+func New(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*App, error) {
+	a := &App{Logger: logger, cfg: cfg, Registry: prometheus.NewRegistry()}
+
+	if err := a.registerMetrics(); err != nil {
+		return nil, fmt.Errorf("register metrics: %w", err)
+	}
+
+	tracer, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	a.tracer, a.shutdownTracing = tracer, shutdownTracing
+
+	db, err := config.ConnectDB(ctx, cfg.Database, logger)
+	if err != nil {
+		shutdownTracing(ctx)
+		return nil, err
+	}
+	a.DB = db
+
+	a.Redis = redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	a.Redis.AddHook(tracing.RedisHook())
+	logger.WithFields(logrus.Fields{
+		"redisAddr": cfg.Redis.Addr,
+		"db":        cfg.Redis.DB,
+	}).Info("Initialized Redis client")
+	a.Analytics = analytics.NewRedisAnalyticsFromClient(a.Redis, logger)
+
+	if err := a.build(ctx); err != nil {
+		a.close(ctx)
+		return nil, err
+	}
+	return a, nil
+}
+
+// build creates the components that depend on the connections.
+func (a *App) build(ctx context.Context) error {
+	logger, cfg := a.Logger, a.cfg
+
+	keys, err := cfg.SigningKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		logger.Warn("TRACKING_KEYS not set. Using an ephemeral key; tokens will not survive restarts or work across replicas")
+		keys = []signing.Key{signing.RandomKey()}
+	}
+	signer, err := signing.NewSigner(keys, cfg.Tracking.TokenTTL, a.Redis, a.metrics.signing, logger)
+	if err != nil {
+		return fmt.Errorf("initialise token signer: %w", err)
+	}
+	a.Signer = signer
+
+	datacenters, err := fraud.LoadCIDRs(cfg.Fraud.DatacenterCIDRs)
+	if err != nil {
+		logger.WithError(err).WithField("file", cfg.Fraud.DatacenterCIDRs).Warn("Failed to load datacenter CIDRs. Datacenter IP rule disabled")
+	}
+	a.Detector = fraud.NewDetector(a.Redis, fraudConfig(cfg.Fraud), datacenters, a.metrics.fraud, logger)
+
+	// instance names this replica to the stream consumer group and the
+	// leader election.
+	instance, _ := os.Hostname()
+	instance = fmt.Sprintf("%s-%d", instance, os.Getpid())
+	a.Elector = leader.NewElector(a.Redis, cfg.Leader.Key, instance, cfg.Leader.Lease, cfg.Leader.RenewInterval, a.metrics.leader, logger)
+
+	switch cfg.Queue.Backend {
+	case "list":
+		a.Queue = queue.NewRedisList(a.Redis, "click", cfg.Queue.ClaimTimeout)
+	case "stream":
+		if a.Queue, err = queue.NewRedisStream(ctx, a.Redis, "click_stream", "workers", instance, cfg.Queue.ClaimTimeout); err != nil {
+			return fmt.Errorf("create Redis stream queue: %w", err)
+		}
+	case "memory":
+		logger.Warn("QUEUE_BACKEND=memory. Queued events are lost on restart and not shared across replicas")
+		a.Queue = queue.NewMemory()
+	default:
+		return fmt.Errorf("invalid queue backend %q, expected list, stream or memory", cfg.Queue.Backend)
+	}
+
+	a.Fallback = clicks.NewFallback(cfg.Queue.FallbackFile)
+
+	if err := a.Registry.Register(worker.NewPipelineCollector(a.Queue, a.Fallback, logger)); err != nil {
+		return fmt.Errorf("register pipeline metrics: %w", err)
+	}
+	a.Ingest = ingest.NewGate(a.Queue, a.Fallback, cfg.Ingest, a.metrics.ingest, logger)
+
+	switch cfg.Selection.Strategy {
+	case "random":
+		a.Strategy = ads.RandomStrategy{}
+	case "thompson":
+		a.thompson = bandit.NewThompson(a.Analytics, cfg.Selection.ExplorationFloor, a.metrics.bandit, logger)
+		a.Strategy = a.thompson
+	default:
+		return fmt.Errorf("invalid ad selection %q, expected random or thompson", cfg.Selection.Strategy)
+	}
+
+	a.Workers = &worker.Pool{
+		Queue:     a.Queue,
+		Fallback:  a.Fallback,
+		Redis:     a.Redis,
+		DB:        a.DB,
+		Analytics: a.Analytics,
+		Detector:  a.Detector,
+		Metrics:   a.metrics.worker,
+		Logger:    logger,
+		Config:    cfg.Worker,
+		Leader:    a.Elector,
+		Tracer:    a.tracer,
+
+		ReserveTimeout: cfg.Queue.ReserveTimeout,
+	}
+	a.Scheduler = scheduler.New(a.Elector, cfg.Scheduler.History, a.metrics.scheduler, logger)
+	if err := a.registerJobs(cfg); err != nil {
+		return fmt.Errorf("register jobs: %w", err)
+	}
+	a.server = &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: a.Router(),
+	}
+	return nil
+}
+
+// registerMetrics registers every package's metrics, plus the HTTP and Go
+// runtime metrics, with the app's own registry.
+func (a *App) registerMetrics() error {
+	a.httpRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total HTTP requests",
+		},
+		[]string{"method", "path", "status"},
+	)
+	a.httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Request duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"path"},
+	)
+
+	reg := a.Registry
+	for _, c := range []prometheus.Collector{
+		a.httpRequestsTotal,
+		a.httpRequestDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	a.metrics = componentMetrics{
+		ads:       ads.NewMetrics(),
+		signing:   signing.NewMetrics(),
+		fraud:     fraud.NewMetrics(),
+		bandit:    bandit.NewMetrics(),
+		worker:    worker.NewMetrics(),
+		ingest:    ingest.NewMetrics(),
+		leader:    leader.NewMetrics(),
+		scheduler: scheduler.NewMetrics(),
+	}
+	for _, register := range []func(prometheus.Registerer) error{
+		a.metrics.ads.Register,
+		a.metrics.signing.Register,
+		a.metrics.fraud.Register,
+		a.metrics.bandit.Register,
+		a.metrics.worker.Register,
+		a.metrics.ingest.Register,
+		a.metrics.leader.Register,
+		a.metrics.scheduler.Register,
+	} {
+		if err := register(reg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts the workers, background jobs and HTTP server. It returns once
+// the server is listening.
+func (a *App) Start() error {
+	ln, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	a.Elector.Start(ctx)
+	a.Ingest.Run(ctx)
+	a.Workers.Start(ctx)
+	a.Scheduler.Start(ctx)
+
+	go func() {
+		a.Logger.WithField("port", a.cfg.Server.Port).Info("Server starting")
+		if err := a.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.Logger.WithError(err).Error("Server stopped unexpectedly")
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the application down in order, within ctx's deadline:
+//
+//  1. /readyz fails for the configured drain delay, giving load balancers
+//     time to notice, then the server stops accepting connections and
+//     finishes in-flight requests;
+//  2. the scheduler stops and the job runs in progress are awaited;
+//  3. the worker pool drains: workers finish or requeue the events they
+//     hold and a final fallback flush and analytics sync run (see
+//     worker.Pool.Drain);
+//  4. the load shedding sampler and the leader election stop, the latter
+//     releasing its lease, and are awaited before the connections are
+//     closed.
+//
+// What was left undrained is logged.
+func (a *App) Stop(ctx context.Context) error {
+	a.draining.Store(true)
+	if delay := a.settings().Server.DrainDelay; delay > 0 {
+		a.Logger.WithField("delay", delay.String()).Info("Draining: reporting not ready")
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		a.Logger.WithError(err).Warn("HTTP server did not finish in-flight requests")
+	}
+
+	if a.cancel != nil {
+		if err := a.Scheduler.Stop(ctx); err != nil {
+			a.Logger.WithError(err).Warn("Background jobs did not stop in time")
+		}
+		a.logDrain(a.Workers.Drain(ctx))
+		a.cancel()
+		a.Elector.Wait()
+		a.Ingest.Wait()
+	}
+	a.close(ctx)
+	return err
+}
+
+func (a *App) logDrain(r worker.DrainReport) {
+	fields := logrus.Fields{
+		"workersFinished": r.Finished,
+		"requeued":        r.Requeued,
+		"fallbackEvents":  r.Fallback,
+		"queueReady":      r.Queue.Ready,
+		"queueInFlight":   r.Queue.InFlight,
+	}
+	for name, err := range map[string]error{"flushError": r.FlushErr, "syncError": r.SyncErr, "queueError": r.QueueErr} {
+		if err != nil {
+			fields[name] = err.Error()
+		}
+	}
+	if r.Clean() {
+		a.Logger.WithFields(fields).Info("Drained background work")
+		return
+	}
+	a.Logger.WithFields(fields).Warn("Shutdown left work undrained")
+}
+
+// settings returns the configuration currently in effect.
+func (a *App) settings() *config.Config {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	return a.cfg
+}
+
+func (a *App) close(ctx context.Context) {
+	if err := a.Redis.Close(); err != nil {
+		a.Logger.WithError(err).Error("Error closing Redis client")
+	}
+	a.DB.Close()
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.Logger.WithError(err).Error("Error flushing traces")
+	}
+}
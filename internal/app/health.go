@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// healthCheck probes one dependency, returning details for the report and
+// an error if it is unhealthy.
+type healthCheck func(ctx context.Context) (detail interface{}, err error)
+
+type checkResult struct {
+	Status string      `json:"status"`
+	Detail interface{} `json:"detail,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// runChecks runs checks concurrently, each bounded by timeout. The report is
+// healthy only if every check passes.
+func runChecks(ctx context.Context, timeout time.Duration, checks map[string]healthCheck) healthReport {
+	report := healthReport{Status: checkOK, Checks: make(map[string]checkResult, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check healthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			detail, err := check(ctx)
+			result := checkResult{Status: checkOK, Detail: detail}
+			if err != nil {
+				result.Status, result.Error = checkFail, err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = checkFail
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+func respond(c *gin.Context, report healthReport) {
+	status := http.StatusOK
+	if report.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// handleHealthz serves GET /healthz. It only fails when the process itself
+// is wedged, i.e. its workers have stopped or stalled, so that an outage of
+// Postgres or Redis does not get every replica restarted.
+func (a *App) handleHealthz(c *gin.Context) {
+	cfg := a.settings()
+	respond(c, runChecks(c.Request.Context(), cfg.Health.CheckTimeout, map[string]healthCheck{
+		"workers": a.checkWorkers(cfg.Health),
+	}))
+}
+
+// handleReadyz serves GET /readyz. It fails while a dependency is down or
+// a backlog is over its threshold, and from the moment shutdown begins.
+func (a *App) handleReadyz(c *gin.Context) {
+	if a.draining.Load() {
+		respond(c, healthReport{Status: checkFail, Checks: map[string]checkResult{
+			"shutdown": {Status: checkFail, Error: "shutting down"},
+		}})
+		return
+	}
+
+	cfg := a.settings()
+	respond(c, runChecks(c.Request.Context(), cfg.Health.CheckTimeout, map[string]healthCheck{
+		"postgres": a.checkPostgres,
+		"redis":    a.checkRedis,
+		"workers":  a.checkWorkers(cfg.Health),
+		"queue":    a.checkQueue(cfg.Health),
+		"fallback": a.checkFallback(cfg.Health),
+	}))
+}
+
+func (a *App) checkPostgres(ctx context.Context) (interface{}, error) {
+	start := time.Now()
+	if err := a.DB.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return gin.H{"latency": time.Since(start).String()}, nil
+}
+
+func (a *App) checkRedis(ctx context.Context) (interface{}, error) {
+	start := time.Now()
+	if err := a.Redis.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return gin.H{"latency": time.Since(start).String()}, nil
+}
+
+func (a *App) checkWorkers(cfg config.HealthConfig) healthCheck {
+	return func(ctx context.Context) (interface{}, error) {
+		status := a.Workers.Status(cfg.WorkerStallTimeout)
+		switch {
+		case !status.Running:
+			return status, fmt.Errorf("worker pool not running")
+		case len(status.Stalled) > 0:
+			return status, fmt.Errorf("%d workers silent for over %s", len(status.Stalled), cfg.WorkerStallTimeout)
+		}
+		return status, nil
+	}
+}
+
+func (a *App) checkQueue(cfg config.HealthConfig) healthCheck {
+	return func(ctx context.Context) (interface{}, error) {
+		depth, err := a.Queue.Depth(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if depth.Ready > int64(cfg.MaxQueueDepth) {
+			return depth, fmt.Errorf("%d events queued, over the limit of %d", depth.Ready, cfg.MaxQueueDepth)
+		}
+		return depth, nil
+	}
+}
+
+func (a *App) checkFallback(cfg config.HealthConfig) healthCheck {
+	return func(ctx context.Context) (interface{}, error) {
+		size, records, err := a.Fallback.Stats()
+		if err != nil {
+			return nil, err
+		}
+		detail := gin.H{"bytes": size, "records": records}
+		if records > cfg.MaxFallbackRecords {
+			return detail, fmt.Errorf("%d events in the fallback file, over the limit of %d", records, cfg.MaxFallbackRecords)
+		}
+		return detail, nil
+	}
+}
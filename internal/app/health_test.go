@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthApp(t *testing.T) (*App, *config.Config) {
+	cfg := config.Default()
+	q := queue.NewMemory()
+	fallback := clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl"))
+	a := &App{
+		Logger:   logs.Discard(),
+		Queue:    q,
+		Fallback: fallback,
+		Workers: &worker.Pool{
+			Queue:          q,
+			Fallback:       fallback,
+			Metrics:        worker.NewMetrics(),
+			Logger:         logs.Discard(),
+			Config:         cfg.Worker,
+			ReserveTimeout: time.Second,
+		},
+		cfg: &cfg,
+	}
+	return a, &cfg
+}
+
+func get(t *testing.T, handler gin.HandlerFunc) (int, healthReport) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler(c)
+
+	var report healthReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestHealthz_Workers(t *testing.T) {
+	a, _ := newHealthApp(t)
+
+	code, report := get(t, a.handleHealthz)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "pool not started")
+	assert.Equal(t, "worker pool not running", report.Checks["workers"].Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); a.Workers.Wait() }()
+	a.Workers.Start(ctx)
+
+	code, report = get(t, a.handleHealthz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, checkOK, report.Status)
+}
+
+func TestReadyz_FailsWhileDraining(t *testing.T) {
+	a, _ := newHealthApp(t)
+	a.draining.Store(true)
+
+	code, report := get(t, a.handleReadyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, checkFail, report.Checks["shutdown"].Status)
+}
+
+func TestCheckQueue_Threshold(t *testing.T) {
+	a, cfg := newHealthApp(t)
+	ctx := context.Background()
+	cfg.Health.MaxQueueDepth = 2
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, a.Queue.Enqueue(ctx, []byte(`{}`)))
+	}
+	_, err := a.checkQueue(cfg.Health)(ctx)
+	assert.NoError(t, err)
+
+	require.NoError(t, a.Queue.Enqueue(ctx, []byte(`{}`)))
+	detail, err := a.checkQueue(cfg.Health)(ctx)
+	assert.EqualError(t, err, "3 events queued, over the limit of 2")
+	assert.Equal(t, queue.Depth{Ready: 3}, detail)
+}
+
+func TestRunChecks(t *testing.T) {
+	report := runChecks(context.Background(), 10*time.Millisecond, map[string]healthCheck{
+		"ok": func(ctx context.Context) (interface{}, error) { return "fine", nil },
+		"down": func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("connection refused")
+		},
+		"slow": func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	assert.Equal(t, checkFail, report.Status)
+	assert.Equal(t, checkResult{Status: checkOK, Detail: "fine"}, report.Checks["ok"])
+	assert.Equal(t, checkResult{Status: checkFail, Error: "connection refused"}, report.Checks["down"])
+	assert.Equal(t, checkResult{Status: checkFail, Error: "context deadline exceeded"}, report.Checks["slow"])
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/scheduler"
+	"github.com/Divyanth2468/video-ad-tracker/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the background jobs.
+const (
+	jobAnalyticsSync = "analytics_sync"
+	jobFallbackFlush = "fallback_flush"
+	jobBanditRefresh = "bandit_refresh"
+)
+
+// registerJobs registers the background jobs with the scheduler. The
+// analytics sync runs on the leader only; the fallback file and the bandit
+// posteriors are local to each replica, so every replica refreshes its own.
+func (a *App) registerJobs(cfg *config.Config) error {
+	schedules, err := cfg.JobSchedules()
+	if err != nil {
+		return err
+	}
+	jobs := []scheduler.Job{
+		{
+			Name:      jobAnalyticsSync,
+			Schedule:  schedules[jobAnalyticsSync],
+			Singleton: true,
+			Run: func(ctx context.Context) error {
+				return worker.SyncRedisAnalyticsToPostgres(ctx, a.Analytics, a.DB, a.Logger)
+			},
+		},
+		{
+			Name:     jobFallbackFlush,
+			Schedule: schedules[jobFallbackFlush],
+			Run:      a.Workers.FlushFallback,
+		},
+	}
+	if a.thompson != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:     jobBanditRefresh,
+			Schedule: schedules[jobBanditRefresh],
+			Run: func(ctx context.Context) error {
+				a.thompson.Refresh(ctx)
+				return nil
+			},
+		})
+	}
+	for _, job := range jobs {
+		job.Jitter = cfg.Scheduler.Jitter
+		job.Timeout = cfg.Scheduler.JobTimeout
+		if err := a.Scheduler.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rescheduleJobs applies changed job schedules on reload.
+func (a *App) rescheduleJobs(previous, next *config.Config) {
+	before, _ := previous.JobSchedules()
+	after, _ := next.JobSchedules()
+	for name, schedule := range after {
+		if before[name] == nil || before[name].String() != schedule.String() {
+			// A job that is not registered, such as bandit_refresh with
+			// random selection, has nothing to reschedule.
+			_ = a.Scheduler.Reschedule(name, schedule)
+		}
+	}
+}
+
+// handleJobs serves GET /admin/jobs.
+func (a *App) handleJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": a.Scheduler.Jobs()})
+}
+
+// handleRunJob serves POST /admin/jobs/:name/run, starting the job now.
+func (a *App) handleRunJob(c *gin.Context) {
+	name := c.Param("name")
+	err := a.Scheduler.Trigger(name)
+	switch {
+	case err == nil:
+		a.Logger.WithField("job", name).Info("Job triggered")
+		c.JSON(http.StatusAccepted, gin.H{"job": name, "status": "started"})
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrStopped):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	}
+}
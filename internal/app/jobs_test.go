@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/Divyanth2468/video-ad-tracker/internal/bandit"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := &App{Logger: logs.Discard(), Scheduler: scheduler.New(nil, 5, scheduler.NewMetrics(), logs.Discard())}
+	ran := make(chan struct{}, 1)
+	require.NoError(t, a.Scheduler.Register(scheduler.Job{
+		Name:     jobFallbackFlush,
+		Schedule: scheduler.Every(time.Hour),
+		Run:      func(context.Context) error { ran <- struct{}{}; return nil },
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Scheduler.Start(ctx)
+	defer func() { cancel(); _ = a.Scheduler.Stop(context.Background()) }()
+
+	r := gin.New()
+	r.GET("/admin/jobs", a.handleJobs)
+	r.POST("/admin/jobs/:name/run", a.handleRunJob)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/jobs/missing/run").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/admin/jobs/fallback_flush/run").Code)
+	<-ran
+	require.Eventually(t, func() bool { return len(a.Scheduler.Jobs()[0].Runs) == 1 }, time.Second, time.Millisecond)
+
+	w := serve(http.MethodGet, "/admin/jobs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"fallback_flush"`)
+	assert.Contains(t, w.Body.String(), `"trigger":"manual"`)
+}
+
+func TestRegisterJobs_BanditRefreshWithThompson(t *testing.T) {
+	cfg := config.Default()
+	names := func(a *App) []string {
+		var names []string
+		for _, job := range a.Scheduler.Jobs() {
+			names = append(names, job.Name)
+		}
+		return names
+	}
+
+	a := &App{Logger: logs.Discard(), Scheduler: scheduler.New(nil, 5, scheduler.NewMetrics(), logs.Discard())}
+	require.NoError(t, a.registerJobs(&cfg))
+	assert.NotContains(t, names(a), jobBanditRefresh)
+
+	a = &App{
+		Logger:    logs.Discard(),
+		Scheduler: scheduler.New(nil, 5, scheduler.NewMetrics(), logs.Discard()),
+		thompson:  bandit.NewThompson(analytics.NewMemoryAnalytics(), 0.05, bandit.NewMetrics(), logs.Discard()),
+	}
+	require.NoError(t, a.registerJobs(&cfg))
+	assert.Contains(t, names(a), jobBanditRefresh)
+}
+
+func TestRegisterJobs_ConfiguredSchedules(t *testing.T) {
+	schedules := func(a *App) map[string]string {
+		out := make(map[string]string)
+		for _, job := range a.Scheduler.Jobs() {
+			out[job.Name] = job.Schedule
+		}
+		return out
+	}
+
+	previous := config.Default()
+	previous.Scheduler.AnalyticsSync = "*/15 * * * *"
+	a := &App{Logger: logs.Discard(), Scheduler: scheduler.New(nil, 5, scheduler.NewMetrics(), logs.Discard())}
+	require.NoError(t, a.registerJobs(&previous))
+	assert.Equal(t, "*/15 * * * *", schedules(a)[jobAnalyticsSync])
+	assert.Equal(t, "@every 1m0s", schedules(a)[jobFallbackFlush], "interval setting when no schedule is set")
+
+	next := previous
+	next.Scheduler.AnalyticsSync = ""
+	next.Scheduler.FallbackFlush = "@hourly"
+	a.rescheduleJobs(&previous, &next)
+	assert.Equal(t, "@every 1m0s", schedules(a)[jobAnalyticsSync])
+	assert.Equal(t, "@hourly", schedules(a)[jobFallbackFlush])
+}
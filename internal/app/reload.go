@@ -0,0 +1,107 @@
+package app
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/fraud"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ReloadResult reports what a reload changed.
+type ReloadResult struct {
+	// Applied are the settings now in effect.
+	Applied []string `json:"applied"`
+	// RestartRequired are changed settings that only take effect on restart.
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// Reload re-reads the configuration with LoadConfig and applies the settings
+// listed in config.Reloadable: the worker pool is resized without dropping
+// the events workers hold, background jobs are rescheduled, and new retry
+// limits, intervals, fraud, health and load shedding thresholds and log
+// level take effect immediately. An invalid configuration is rejected as a
+// whole.
+func (a *App) Reload() (ReloadResult, error) {
+	if a.LoadConfig == nil {
+		return ReloadResult{}, errors.New("reload not supported")
+	}
+	next, err := a.LoadConfig()
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	result := ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, path := range a.cfg.Changed(next) {
+		if config.Reloadable[path] {
+			result.Applied = append(result.Applied, path)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, path)
+		}
+	}
+
+	previous := a.cfg
+	applied := *a.cfg
+	applied.Worker = next.Worker
+	applied.Scheduler.AnalyticsSync = next.Scheduler.AnalyticsSync
+	applied.Scheduler.FallbackFlush = next.Scheduler.FallbackFlush
+	applied.Scheduler.BanditRefresh = next.Scheduler.BanditRefresh
+	applied.Fraud.MaxClicksPerMinute = next.Fraud.MaxClicksPerMinute
+	applied.Fraud.MinClickDelay = next.Fraud.MinClickDelay
+	applied.Fraud.Threshold = next.Fraud.Threshold
+	applied.Log.Level = next.Log.Level
+	applied.Health = next.Health
+	applied.Ingest = next.Ingest
+	a.cfg = &applied
+
+	level, _ := logrus.ParseLevel(applied.Log.Level)
+	a.Logger.SetLevel(level)
+	a.Detector.SetConfig(fraudConfig(applied.Fraud))
+	a.Workers.Reload(applied.Worker)
+	a.rescheduleJobs(previous, &applied)
+	a.Ingest.SetConfig(applied.Ingest)
+
+	a.Logger.WithFields(logrus.Fields{
+		"applied":         result.Applied,
+		"restartRequired": result.RestartRequired,
+	}).Info("Configuration reloaded")
+	return result, nil
+}
+
+func fraudConfig(cfg config.FraudConfig) fraud.Config {
+	return fraud.Config{
+		MaxClicksPerMinute: cfg.MaxClicksPerMinute,
+		MinClickDelay:      cfg.MinClickDelay,
+		Threshold:          cfg.Threshold,
+	}
+}
+
+// handleReload serves POST /admin/reload.
+func (a *App) handleReload(c *gin.Context) {
+	result, err := a.Reload()
+	if err != nil {
+		a.Logger.WithError(err).Warn("Configuration reload rejected")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// requireAdmin admits requests bearing the configured admin token.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
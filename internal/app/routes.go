@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/experiments"
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracing"
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracking"
+	"github.com/Divyanth2468/video-ad-tracker/internal/vast"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Router builds the HTTP routes, wired to the app's dependencies.
+func (a *App) Router() *gin.Engine {
+	r := gin.Default()
+	// Validated with the config; with no trusted proxies ClientIP is the
+	// peer address.
+	_ = r.SetTrustedProxies(a.cfg.Server.Proxies())
+	r.Use(tracing.Middleware(a.tracer), a.prometheusMiddleware())
+	r.Static("/assets", "./web/assets")
+	r.LoadHTMLFiles("web/index.html")
+
+	r.GET("/", func(c *gin.Context) {
+		c.HTML(200, "index.html", nil)
+	})
+
+	r.GET("/ads", ads.GetAdHandler(a.DB, a.Signer, a.Strategy, a.metrics.ads, a.Logger))
+	r.GET("/vast", vast.GetVASTHandler(a.DB, a.Signer, a.Strategy, a.cfg.Server.PublicURL, a.Logger))
+	clickHandler := &clicks.ClickHandler{DB: a.DB, Queue: a.Queue, Fallback: a.Fallback, Tokens: a.Signer, Logger: a.Logger}
+	click := a.Ingest.Admit(clicks.KindClick)
+	r.POST("/ads/click", click, clickHandler.HandlerClick)
+	r.GET("/c/:token", click, clickHandler.HandleRedirect)
+
+	pixelHandler := &tracking.PixelHandler{Impressions: a.Redis, Queue: a.Queue, Fallback: a.Fallback, Tokens: a.Signer, Logger: a.Logger}
+	impression := a.Ingest.Admit(clicks.KindImpression)
+	r.POST("/ads/impression", impression, pixelHandler.HandleImpressionJSON)
+	r.GET("/t/imp", impression, pixelHandler.HandleImpression)
+	r.POST("/t/imp", impression, pixelHandler.HandleImpression)
+	video := a.Ingest.Admit(clicks.KindVideo)
+	r.GET("/t/evt", video, pixelHandler.HandleEvent)
+	r.POST("/t/evt", video, pixelHandler.HandleEvent)
+	conversion := a.Ingest.Admit(clicks.KindConversion)
+	r.POST("/conversions", conversion, pixelHandler.HandleConversion)
+	r.GET("/conversions", conversion, pixelHandler.HandleConversion)
+
+	r.GET("/ads/analytics", analytics.GetAnalyticsHandler(a.Analytics, a.Logger))
+	r.GET("/experiments/:id/analytics", experiments.GetExperimentAnalyticsHandler(a.DB, a.Analytics, a.Logger))
+	r.GET("/healthz", a.handleHealthz)
+	r.GET("/readyz", a.handleReadyz)
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(a.Registry, promhttp.HandlerOpts{})))
+
+	if a.cfg.Server.AdminToken != "" {
+		admin := r.Group("/admin", requireAdmin(a.cfg.Server.AdminToken))
+		admin.POST("/reload", a.handleReload)
+		admin.GET("/jobs", a.handleJobs)
+		admin.POST("/jobs/:name/run", a.handleRunJob)
+	}
+
+	return r
+}
+
+func (a *App) prometheusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		duration := time.Since(start)
+
+		status := fmt.Sprintf("%d", c.Writer.Status())
+		a.httpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath(), status).Inc()
+		a.httpRequestDuration.WithLabelValues(c.FullPath()).Observe(duration.Seconds())
+	}
+}
@@ -0,0 +1,48 @@
+package bandit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics report the allocation of a Thompson strategy.
+type Metrics struct {
+	selections      *prometheus.CounterVec
+	posteriorMean   *prometheus.GaugeVec
+	refreshFailures prometheus.Counter
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		selections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bandit_selections_total",
+				Help: "Times each creative was chosen by the bandit; rate() gives allocation over time",
+			},
+			[]string{"ad_id", "mode"},
+		),
+		posteriorMean: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "bandit_posterior_ctr",
+				Help: "Posterior mean CTR of each creative at the last refresh",
+			},
+			[]string{"ad_id"},
+		),
+		refreshFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "bandit_refresh_failures_total",
+				Help: "Posterior refreshes that could not read counters",
+			},
+		),
+	}
+}
+
+// Register registers bandit allocation metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.selections, m.posteriorMean, m.refreshFailures} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
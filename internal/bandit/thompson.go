@@ -0,0 +1,155 @@
+package bandit
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Counters reads the per-creative totals the posteriors are built from.
+// *analytics.RedisAnalytics satisfies it.
+type Counters interface {
+	GetTotalImpressions(ctx context.Context, adId string) (int, error)
+	GetTotalClicks(ctx context.Context, adId string) (int, error)
+}
+
+// posterior is Beta(alpha, beta) over a creative's click probability.
+type posterior struct {
+	alpha, beta float64
+}
+
+// Thompson serves creatives by Thompson sampling over Beta-Bernoulli
+// posteriors of their CTR: valid clicks are successes, impressions without a
+// click failures, starting from a uniform Beta(1, 1) prior. A share of
+// traffic set by the exploration floor is served uniformly at random, so no
+// creative is starved by an early unlucky streak.
+type Thompson struct {
+	counters Counters
+	floor    float64
+	metrics  *Metrics
+	logger   logrus.FieldLogger
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	posteriors map[string]posterior
+}
+
+// NewThompson builds a Thompson sampler. floor is the minimum share of
+// traffic each creative receives, e.g. 0.05; with K creatives, a fraction
+// floor*K of selections is uniform.
+func NewThompson(counters Counters, floor float64, metrics *Metrics, logger logrus.FieldLogger) *Thompson {
+	return &Thompson{
+		counters:   counters,
+		floor:      floor,
+		metrics:    metrics,
+		logger:     logger,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		posteriors: make(map[string]posterior),
+	}
+}
+
+// Choose picks one of adIDs. Creatives not seen before start at the prior
+// and are picked up by the next refresh.
+func (t *Thompson) Choose(adIDs []string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range adIDs {
+		if _, ok := t.posteriors[id]; !ok {
+			t.posteriors[id] = posterior{alpha: 1, beta: 1}
+		}
+	}
+
+	if t.rng.Float64() < t.floor*float64(len(adIDs)) {
+		chosen := adIDs[t.rng.Intn(len(adIDs))]
+		t.metrics.selections.WithLabelValues(chosen, "explore").Inc()
+		return chosen
+	}
+
+	chosen, best := adIDs[0], -1.0
+	for _, id := range adIDs {
+		p := t.posteriors[id]
+		if sample := sampleBeta(t.rng, p.alpha, p.beta); sample > best {
+			chosen, best = id, sample
+		}
+	}
+	t.metrics.selections.WithLabelValues(chosen, "exploit").Inc()
+	return chosen
+}
+
+// Refresh rebuilds the posteriors of every known creative from the counters.
+// A creative whose counters cannot be read keeps its previous posterior.
+func (t *Thompson) Refresh(ctx context.Context) {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.posteriors))
+	for id := range t.posteriors {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+
+	updated := make(map[string]posterior, len(ids))
+	for _, id := range ids {
+		impressions, err := t.counters.GetTotalImpressions(ctx, id)
+		if err != nil {
+			t.metrics.refreshFailures.Inc()
+			continue
+		}
+		clicks, err := t.counters.GetTotalClicks(ctx, id)
+		if err != nil {
+			t.metrics.refreshFailures.Inc()
+			continue
+		}
+		p := posteriorFor(impressions, clicks)
+		updated[id] = p
+		t.metrics.posteriorMean.WithLabelValues(id).Set(p.alpha / (p.alpha + p.beta))
+	}
+
+	t.mu.Lock()
+	for id, p := range updated {
+		t.posteriors[id] = p
+	}
+	t.mu.Unlock()
+}
+
+// posteriorFor turns counters into a posterior. Clicks are capped at
+// impressions, since clicks whose impression was never counted would
+// otherwise make the failure count negative.
+func posteriorFor(impressions, clicks int) posterior {
+	if clicks > impressions {
+		clicks = impressions
+	}
+	return posterior{alpha: 1 + float64(clicks), beta: 1 + float64(impressions-clicks)}
+}
+
+// sampleBeta draws from Beta(a, b) as X/(X+Y) with X ~ Gamma(a), Y ~ Gamma(b).
+func sampleBeta(rng *rand.Rand, a, b float64) float64 {
+	x := sampleGamma(rng, a)
+	y := sampleGamma(rng, b)
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1) using Marsaglia and Tsang's method.
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// Boost to shape+1 and scale back down.
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bandit
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCounters struct {
+	impressions map[string]int
+	clicks      map[string]int
+	err         error
+}
+
+func (f *fakeCounters) GetTotalImpressions(_ context.Context, adId string) (int, error) {
+	return f.impressions[adId], f.err
+}
+
+func (f *fakeCounters) GetTotalClicks(_ context.Context, adId string) (int, error) {
+	return f.clicks[adId], f.err
+}
+
+func allocation(t *Thompson, ids []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[t.Choose(ids)]++
+	}
+	return counts
+}
+
+func TestThompson_FavoursBetterCreative(t *testing.T) {
+	counters := &fakeCounters{
+		impressions: map[string]int{"good": 5000, "poor": 5000},
+		clicks:      map[string]int{"good": 400, "poor": 100},
+	}
+	ts := NewThompson(counters, 0, NewMetrics(), logs.Discard())
+	ids := []string{"good", "poor"}
+
+	// Before the first refresh both creatives sit at the prior.
+	counts := allocation(ts, ids, 2000)
+	assert.InDelta(t, 1000, counts["good"], 200)
+
+	ts.Refresh(context.Background())
+	counts = allocation(ts, ids, 2000)
+	assert.Greater(t, counts["good"], 1980)
+}
+
+func TestThompson_ExplorationFloor(t *testing.T) {
+	counters := &fakeCounters{
+		impressions: map[string]int{"good": 5000, "poor": 5000},
+		clicks:      map[string]int{"good": 400, "poor": 100},
+	}
+	ts := NewThompson(counters, 0.05, NewMetrics(), logs.Discard())
+	ids := []string{"good", "poor"}
+	ts.Choose(ids)
+	ts.Refresh(context.Background())
+
+	counts := allocation(ts, ids, 10000)
+	assert.InDelta(t, 500, counts["poor"], 120)
+}
+
+func TestThompson_RefreshKeepsPosteriorOnError(t *testing.T) {
+	counters := &fakeCounters{
+		impressions: map[string]int{"a": 100},
+		clicks:      map[string]int{"a": 10},
+	}
+	ts := NewThompson(counters, 0, NewMetrics(), logs.Discard())
+	ts.Choose([]string{"a"})
+	ts.Refresh(context.Background())
+
+	counters.err = errors.New("redis down")
+	ts.Refresh(context.Background())
+	assert.Equal(t, posterior{alpha: 11, beta: 91}, ts.posteriors["a"])
+}
+
+func TestPosteriorFor_CapsClicks(t *testing.T) {
+	assert.Equal(t, posterior{alpha: 4, beta: 1}, posteriorFor(3, 7))
+}
+
+func TestSampleBeta_Mean(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range []struct{ a, b float64 }{{2, 8}, {0.5, 0.5}, {50, 950}} {
+		var sum float64
+		for i := 0; i < 20000; i++ {
+			sum += sampleBeta(rng, tc.a, tc.b)
+		}
+		assert.InDelta(t, tc.a/(tc.a+tc.b), sum/20000, 0.01)
+	}
+}
@@ -3,14 +3,50 @@ package clicks
 import (
 	"context"
 
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func InsertClickEvent(ctx context.Context, db *pgxpool.Pool, event ClickEvent) error {
+	ctx, span := tracing.Start(ctx, "db.InsertClickEvent",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.collection.name", "click_events")),
+	)
+	defer span.End()
+
+	_, err := db.Exec(ctx,
+		`INSERT INTO click_events (id, ad_id, impression_id, viewer_id, experiment_id, arm, timestamp, ip_address,
+		                           video_playback_time, user_agent, fraud_score, fraud_reasons, is_valid)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, ''), NULLIF($5, '')::uuid, NULLIF($6, ''), $7, $8,
+		         $9, $10, $11, $12, $13)
+		 ON CONFLICT (id) DO NOTHING;`,
+		event.ID, event.AdID, event.ImpressionID, event.ViewerID, event.ExperimentID, event.Arm, event.Timestamp, event.IPAddress,
+		event.VideoPlaybackTime, event.UserAgent, event.FraudScore, event.FraudReasons, !event.Invalid)
+	if err != nil {
+		tracing.Fail(span, err)
+	}
+	return err
+}
+
+func InsertVideoEvent(ctx context.Context, db *pgxpool.Pool, event VideoEvent) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO video_events (id, ad_id, event_type, timestamp, ip_address, playback_time)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (id) DO NOTHING;`,
+		event.ID, event.AdID, event.Type, event.Timestamp, event.IPAddress, event.PlaybackTime)
+	return err
+}
+
+// InsertImpression persists an impression. It is safe to call more than once
+// for the same impression, which lets click processing insert the row first
+// when it overtakes the impression in the queue.
+func InsertImpression(ctx context.Context, db *pgxpool.Pool, imp ImpressionEvent) error {
 	_, err := db.Exec(ctx,
-		`INSERT INTO click_events (id, ad_id, timestamp, ip_address, video_playback_time)
-		 VALUES ($1, $2, $3, $4, $5)
+		`INSERT INTO impressions (id, ad_id, viewer_id, experiment_id, arm, timestamp, ip_address)
+		 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, '')::uuid, NULLIF($5, ''), $6, $7)
 		 ON CONFLICT (id) DO NOTHING;`,
-		event.ID, event.AdID, event.Timestamp, event.IPAddress, event.VideoPlaybackTime)
+		imp.ID, imp.AdID, imp.ViewerID, imp.ExperimentID, imp.Arm, imp.Timestamp, imp.IPAddress)
 	return err
 }
@@ -0,0 +1,81 @@
+package clicks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fallback is the file that holds events which could not be queued, one JSON
+// object per line, until the workers flush them back to the queue.
+type Fallback struct {
+	Path string
+}
+
+// NewFallback returns the fallback file at path.
+func NewFallback(path string) *Fallback {
+	return &Fallback{Path: path}
+}
+
+// Write appends an event to the file.
+func (f *Fallback) Write(event RetryableClick, logger logrus.FieldLogger) error {
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.WithError(err).Error("Failed to open fallback file")
+		return err
+	}
+	defer file.Close()
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.WithError(err).Error("Failed to marshal click event for fallback")
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		logger.WithError(err).Error("Failed to write click to fallback file")
+		return err
+	}
+	return nil
+}
+
+// Size returns the size of the file, zero when there is none. Unlike Stats
+// it does not read the file.
+func (f *Fallback) Size() (int64, error) {
+	info, err := os.Stat(f.Path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Stats returns the size of the file and the number of events in it; both
+// are zero when there is no file.
+func (f *Fallback) Stats() (size int64, records int, err error) {
+	file, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := file.Read(buf)
+		size += int64(n)
+		records += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return size, records, nil
+		}
+		if err != nil {
+			return size, records, err
+		}
+	}
+}
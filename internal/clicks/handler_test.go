@@ -2,16 +2,22 @@ package clicks
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func setupRouter(handler *ClickHandler) *gin.Engine {
@@ -20,44 +26,116 @@ func setupRouter(handler *ClickHandler) *gin.Engine {
 	return r
 }
 
+func newTestSigner(t *testing.T) *signing.Signer {
+	signer, err := signing.NewSigner([]signing.Key{{ID: "test", Secret: []byte("secret")}}, time.Hour, nil, signing.NewMetrics(), logs.Discard())
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	return signer
+}
+
+type clickRequest struct {
+	ClickEvent
+	Token string `json:"token"`
+}
+
 func TestHandlerClick_ValidInput(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	// Mock Redis client that always succeeds
-	mockRedis := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
-
+	q := queue.NewMemory()
+	signer := newTestSigner(t)
 	handler := &ClickHandler{
-		Redis: &analytics.RedisAnalytics{Client: mockRedis},
+		Queue:  q,
+		Tokens: signer,
+		Logger: logs.Discard(),
 	}
 
 	router := setupRouter(handler)
 
-	click := ClickEvent{
-		AdID:              "11111111-1111-1111-1111-111111111111",
-		Timestamp:         time.Now(),
-		IPAddress:         "127.0.0.1",
-		VideoPlaybackTime: 10.5,
+	tok, token, _ := signer.Issue("11111111-1111-1111-1111-111111111111", "viewer-1")
+	click := clickRequest{
+		ClickEvent: ClickEvent{
+			AdID:              "11111111-1111-1111-1111-111111111111",
+			Timestamp:         time.Now().Add(-time.Hour),
+			IPAddress:         "203.0.113.9",
+			VideoPlaybackTime: 10.5,
+		},
+		Token: token,
 	}
 
 	body, _ := json.Marshal(click)
 	req, _ := http.NewRequest(http.MethodPost, "/ads/click", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:41000"
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	msg, err := q.Reserve(context.Background())
+	assert.NoError(t, err)
+	var queued RetryableClick
+	assert.NoError(t, json.Unmarshal(msg.Body, &queued))
+	assert.Equal(t, KindClick, queued.Kind)
+	assert.Equal(t, tok.ImpressionID, queued.Event.ImpressionID)
+	assert.Equal(t, "viewer-1", queued.Event.ViewerID)
+	assert.Equal(t, "192.0.2.1", queued.Event.IPAddress, "the IP is the request's, not the payload's")
+	assert.WithinDuration(t, time.Now(), queued.Event.Timestamp, time.Minute)
+}
+
+func TestEnqueue_CarriesTraceContext(t *testing.T) {
+	q := queue.NewMemory()
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "POST /ads/click")
+	defer span.End()
+
+	fallback, err := Enqueue(ctx, q, nil, RetryableClick{Kind: KindClick}, logs.Discard())
+	require.NoError(t, err)
+	assert.False(t, fallback)
+
+	msg, err := q.Reserve(context.Background())
+	require.NoError(t, err)
+	var queued RetryableClick
+	require.NoError(t, json.Unmarshal(msg.Body, &queued))
+	assert.Contains(t, queued.TraceContext["traceparent"], span.SpanContext().TraceID().String())
+	assert.False(t, queued.EnqueuedAt.IsZero())
+}
+
+func TestHandlerClick_ForgedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ClickHandler{
+		Queue:  queue.NewMemory(),
+		Tokens: newTestSigner(t),
+		Logger: logs.Discard(),
+	}
+
+	router := setupRouter(handler)
+
+	// Token issued for a different ad, then signed by a key the handler does not know.
+	other, _ := signing.NewSigner([]signing.Key{{ID: "test", Secret: []byte("other")}}, time.Hour, nil, signing.NewMetrics(), logs.Discard())
+	_, forged, _ := other.Issue("11111111-1111-1111-1111-111111111111", "viewer-1")
+
+	body, _ := json.Marshal(clickRequest{
+		ClickEvent: ClickEvent{AdID: "11111111-1111-1111-1111-111111111111", IPAddress: "127.0.0.1"},
+		Token:      forged,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/ads/click", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
 }
 
 func TestHandlerClick_InvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	handler := &ClickHandler{
-		Redis: &analytics.RedisAnalytics{Client: redis.NewClient(&redis.Options{
-			Addr: "localhost:6379",
-		})},
+		Queue:  queue.NewMemory(),
+		Tokens: newTestSigner(t),
+		Logger: logs.Discard(),
 	}
 
 	router := setupRouter(handler)
@@ -70,3 +148,31 @@ func TestHandlerClick_InvalidJSON(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestExpandTargetURL(t *testing.T) {
+	ad := ads.Ad{
+		ID:         "11111111-1111-1111-1111-111111111111",
+		CampaignID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		TargetURL:  "https://product.example/landing?ref={click_id}&c={campaign_id}&utm_source=partner",
+	}
+
+	target, err := ExpandTargetURL(ad, "click-1", url.Values{"utm_medium": {"ctv"}, "utm_source": {"ignored"}})
+	assert.NoError(t, err)
+
+	u, err := url.Parse(target)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "click-1", q.Get("ref"))
+	assert.Equal(t, ad.CampaignID, q.Get("c"))
+	assert.Equal(t, "partner", q.Get("utm_source"))
+	assert.Equal(t, "ctv", q.Get("utm_medium"))
+	assert.Equal(t, ad.CampaignID, q.Get("utm_campaign"))
+	assert.Equal(t, ad.ID, q.Get("utm_content"))
+}
+
+func TestExpandTargetURL_Invalid(t *testing.T) {
+	for _, target := range []string{"", "/landing", "javascript:alert(1)", "ftp://files.example/ad", "https://", "http://[::1"} {
+		_, err := ExpandTargetURL(ads.Ad{ID: "ad-1", TargetURL: target}, "click-1", nil)
+		assert.Error(t, err, target)
+	}
+}
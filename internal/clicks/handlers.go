@@ -1,50 +1,124 @@
 package clicks
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
-	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ClickHandler struct {
-	DB    *pgxpool.Pool
-	Redis *analytics.RedisAnalytics
+	DB       *pgxpool.Pool
+	Queue    queue.Queue
+	Fallback *Fallback
+	Tokens   *signing.Signer
+	Logger   logrus.FieldLogger
 }
 
-var logger = logs.Logger
-
 type RetryableClick struct {
-	Event ClickEvent `json:"event"`
-	Retry int        `json:"retry"`
+	Kind       string           `json:"kind,omitempty"`
+	Event      ClickEvent       `json:"event"`
+	Impression *ImpressionEvent `json:"impression,omitempty"`
+	Video      *VideoEvent      `json:"video,omitempty"`
+	Conversion *ConversionEvent `json:"conversion,omitempty"`
+	Retry      int              `json:"retry"`
+	// EnqueuedAt is when the event was first queued; retries keep it.
+	EnqueuedAt time.Time `json:"enqueuedAt,omitempty"`
+	// TraceContext carries the W3C trace context of the request that queued
+	// the event, so the worker's span can link back to it.
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+}
+
+// EventKind reports the kind of the wrapped event, treating an empty kind as a click.
+func (r RetryableClick) EventKind() string {
+	if r.Kind == "" {
+		return KindClick
+	}
+	return r.Kind
+}
+
+// AdID returns the ad the wrapped event belongs to, whatever its kind.
+// Conversions are only tied to an ad once attributed, so theirs is empty.
+func (r RetryableClick) AdID() string {
+	if r.Impression != nil {
+		return r.Impression.AdID
+	}
+	if r.Video != nil {
+		return r.Video.AdID
+	}
+	if r.Conversion != nil {
+		return ""
+	}
+	return r.Event.AdID
+}
+
+// OccurredAt returns when the wrapped event happened, whatever its kind.
+func (r RetryableClick) OccurredAt() time.Time {
+	switch {
+	case r.Impression != nil:
+		return r.Impression.Timestamp
+	case r.Video != nil:
+		return r.Video.Timestamp
+	case r.Conversion != nil:
+		return r.Conversion.Timestamp
+	}
+	return r.Event.Timestamp
 }
 
 func (h *ClickHandler) HandlerClick(c *gin.Context) {
-	var event ClickEvent
+	ctx, span := tracing.Start(c.Request.Context(), "clicks.HandlerClick")
+	defer span.End()
+
+	var req struct {
+		ClickEvent
+		Token string `json:"token"`
+	}
 
-	if err := c.ShouldBindJSON(&event); err != nil {
-		logger.WithError(err).Warn("Invalid click payload")
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.Logger.WithError(err).Warn("Invalid click payload")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	event := req.ClickEvent
 
-	if event.AdID == "" || event.IPAddress == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing adId or ipAddress"})
+	if event.AdID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing adId"})
 		return
 	}
 
-	event.ID = uuid.New().String()
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
+	span.SetAttributes(attribute.String("ad.id", event.AdID))
+	tok, err := h.Tokens.Validate(ctx, req.Token, event.AdID, KindClick, true)
+	if err != nil {
+		h.Logger.WithError(err).WithField("adId", event.AdID).Warn("Rejected click token")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or reused tracking token"})
+		return
 	}
+	if event.ImpressionID != "" && event.ImpressionID != tok.ImpressionID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "impressionId does not match tracking token"})
+		return
+	}
+	event.ImpressionID = tok.ImpressionID
+	event.ViewerID = tok.ViewerID
+	event.ExperimentID, event.Arm = tok.ExperimentID, tok.Arm
+
+	// Fraud scoring relies on the client IP and the click time, so both are
+	// taken from the request rather than the payload.
+	event.ID = uuid.New().String()
+	event.UserAgent = c.Request.UserAgent()
+	event.IPAddress = c.ClientIP()
+	event.Timestamp = time.Now()
 
-	logger.WithFields(map[string]interface{}{
+	h.Logger.WithFields(map[string]interface{}{
 		"adId":      event.AdID,
 		"ip":        event.IPAddress,
 		"timestamp": event.Timestamp.Format(time.RFC3339),
@@ -52,44 +126,56 @@ func (h *ClickHandler) HandlerClick(c *gin.Context) {
 	}).Info("Received click event")
 
 	wrapper := RetryableClick{
+		Kind:  KindClick,
 		Event: event,
 		Retry: 0,
 	}
 
-	data, err := json.Marshal(wrapper)
+	fallback, err := Enqueue(ctx, h.Queue, h.Fallback, wrapper, h.Logger)
 	if err != nil {
-		logger.WithError(err).WithField("adId", event.AdID).Error("Failed to serialize click event")
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to serialize click"})
+		tracing.Fail(span, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to queue click"})
 		return
 	}
-
-	if err := h.Redis.Client.LPush(c.Request.Context(), "click_queue", data).Err(); err != nil {
-		logger.WithError(err).WithField("adId", event.AdID).Error("Failed to push click event to Redis queue")
-		FallbackToDisk(wrapper)
+	if fallback {
 		c.JSON(http.StatusAccepted, gin.H{"message": "Queued via fallback"})
 		return
 	}
 
-	logger.WithField("adId", event.AdID).Info("Click event pushed to Redis")
+	h.Logger.WithField("adId", event.AdID).Info("Click event queued")
 
 	c.JSON(http.StatusAccepted, gin.H{"message": "Click event queued"})
 }
 
-func FallbackToDisk(event RetryableClick) {
-	f, err := os.OpenFile("fallback_clicks.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		logger.WithError(err).Error("Failed to open fallback file")
-		return
+// Enqueue puts an event on the event queue. When the queue is unreachable
+// the event is written to fb instead and fallback is true; an error means
+// the event was recorded nowhere.
+func Enqueue(ctx context.Context, q queue.Queue, fb *Fallback, wrapper RetryableClick, logger logrus.FieldLogger) (fallback bool, err error) {
+	ctx, span := tracing.Start(ctx, "queue.enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("event.kind", wrapper.EventKind())),
+	)
+	defer span.End()
+
+	if wrapper.EnqueuedAt.IsZero() {
+		wrapper.EnqueuedAt = time.Now().UTC()
 	}
-	defer f.Close()
-	logger.Printf("fall back file opened")
-	data, err := json.Marshal(event)
+	if wrapper.TraceContext == nil {
+		wrapper.TraceContext = tracing.Inject(ctx)
+	}
+	data, err := json.Marshal(wrapper)
 	if err != nil {
-		logger.WithError(err).Error("Failed to marshal click event for fallback")
-		return
+		logger.WithError(err).WithField("adId", wrapper.AdID()).Error("Failed to serialize queued event")
+		return false, err
 	}
 
-	if _, err := f.Write(append(data, '\n')); err != nil {
-		logger.WithError(err).Error("Failed to write click to fallback file")
+	if err := q.Enqueue(ctx, data); err != nil {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"adId": wrapper.AdID(),
+			"kind": wrapper.EventKind(),
+		}).Error("Failed to push event to queue")
+		span.AddEvent("fallback to disk")
+		return true, fb.Write(wrapper, logger)
 	}
+	return false, nil
 }
@@ -0,0 +1,56 @@
+package clicks
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ImpressionRecordTTL is how long an impression stays attributable in Redis.
+// It outlives tracking tokens, so any click with a valid token can still be
+// matched to its impression.
+const ImpressionRecordTTL = 24 * time.Hour
+
+// RecordImpression stores the impression under its ID so that clicks carrying
+// the same impression ID can be validated and attributed to it.
+func RecordImpression(ctx context.Context, rdb *redis.Client, imp ImpressionEvent) error {
+	key := "impression:" + imp.ID
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"adId":   imp.AdID,
+		"viewer": imp.ViewerID,
+		"exp":    imp.ExperimentID,
+		"arm":    imp.Arm,
+		"ts":     imp.Timestamp.UnixMilli(),
+		"ip":     imp.IPAddress,
+	})
+	pipe.Expire(ctx, key, ImpressionRecordTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LookupImpression returns the recorded impression, or nil if it is unknown or has expired.
+func LookupImpression(ctx context.Context, rdb *redis.Client, id string) (*ImpressionEvent, error) {
+	if id == "" {
+		return nil, nil
+	}
+	fields, err := rdb.HGetAll(ctx, "impression:"+id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	ms, _ := strconv.ParseInt(fields["ts"], 10, 64)
+	return &ImpressionEvent{
+		ID:           id,
+		AdID:         fields["adId"],
+		ViewerID:     fields["viewer"],
+		ExperimentID: fields["exp"],
+		Arm:          fields["arm"],
+		Timestamp:    time.UnixMilli(ms),
+		IPAddress:    fields["ip"],
+	}, nil
+}
@@ -2,10 +2,60 @@ package clicks
 
 import "time"
 
+// Kinds of event carried through click_queue. An empty kind is a click, so
+// payloads queued before the field existed are still processed.
+const (
+	KindClick      = "click"
+	KindImpression = "impression"
+	KindVideo      = "video"
+	KindConversion = "conversion"
+)
+
 type ClickEvent struct {
 	ID                string    `json:"id"`
 	AdID              string    `json:"adId"`
+	ImpressionID      string    `json:"impressionId,omitempty"`
+	ViewerID          string    `json:"viewerId,omitempty"`
+	ExperimentID      string    `json:"experimentId,omitempty"`
+	Arm               string    `json:"arm,omitempty"`
 	Timestamp         time.Time `json:"timestamp"`
 	IPAddress         string    `json:"ipAddress"`
 	VideoPlaybackTime float64   `json:"videoPlaybackTime"`
+	UserAgent         string    `json:"userAgent,omitempty"`
+
+	// Set by the worker's fraud scoring stage before the click is persisted.
+	FraudScore   float64  `json:"fraudScore,omitempty"`
+	FraudReasons []string `json:"fraudReasons,omitempty"`
+	Invalid      bool     `json:"invalid,omitempty"`
+}
+
+type ImpressionEvent struct {
+	ID           string    `json:"id"`
+	AdID         string    `json:"adId"`
+	ViewerID     string    `json:"viewerId,omitempty"`
+	ExperimentID string    `json:"experimentId,omitempty"`
+	Arm          string    `json:"arm,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	IPAddress    string    `json:"ipAddress"`
+}
+
+type VideoEvent struct {
+	ID           string    `json:"id"`
+	AdID         string    `json:"adId"`
+	Type         string    `json:"type"`
+	Timestamp    time.Time `json:"timestamp"`
+	IPAddress    string    `json:"ipAddress"`
+	PlaybackTime float64   `json:"playbackTime"`
+}
+
+// ConversionEvent is an advertiser-reported conversion. It names the click
+// or the viewer it came from; the worker attributes it to an ad.
+type ConversionEvent struct {
+	ID        string    `json:"id"`
+	ClickID   string    `json:"clickId,omitempty"`
+	ViewerID  string    `json:"viewerId,omitempty"`
+	OrderID   string    `json:"orderId,omitempty"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+	IPAddress string    `json:"ipAddress"`
 }
@@ -0,0 +1,136 @@
+package clicks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Default UTM values appended to target URLs that do not set their own.
+const (
+	utmSource = "video-ad-tracker"
+	utmMedium = "video"
+)
+
+// HandleRedirect records a click for the ad named by the signed :token
+// through the regular queue path, then 302-redirects the viewer to the ad's
+// target URL. The redirect is only issued once the click is queued (or
+// written to the fallback file), so every redirect is counted, and a click
+// is only queued once its target URL is known to be valid, so every counted
+// click is redirected.
+func (h *ClickHandler) HandleRedirect(c *gin.Context) {
+	tok, err := h.Tokens.Validate(c.Request.Context(), c.Param("token"), "", KindClick, true)
+	replayed := errors.Is(err, signing.ErrReplayed)
+	if replayed {
+		// A repeated click on the same served ad still reaches the
+		// advertiser, it just isn't counted again.
+		tok, err = h.Tokens.Verify(c.Param("token"))
+	}
+	if err != nil {
+		h.Logger.WithError(err).Warn("Rejected click redirect token")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid tracking token"})
+		return
+	}
+	adID := tok.AdID
+
+	ad, err := ads.GetAdByID(c.Request.Context(), h.DB, adID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown ad"})
+		return
+	}
+	if err != nil {
+		h.Logger.WithError(err).WithField("adId", adID).Error("Failed to load ad for redirect")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ad"})
+		return
+	}
+
+	playback, _ := strconv.ParseFloat(c.Query("t"), 64)
+	event := ClickEvent{
+		ID:                uuid.New().String(),
+		AdID:              ad.ID,
+		ImpressionID:      tok.ImpressionID,
+		ViewerID:          tok.ViewerID,
+		ExperimentID:      tok.ExperimentID,
+		Arm:               tok.Arm,
+		Timestamp:         time.Now(),
+		IPAddress:         c.ClientIP(),
+		VideoPlaybackTime: playback,
+		UserAgent:         c.Request.UserAgent(),
+	}
+
+	target, err := ExpandTargetURL(ad, event.ID, c.Request.URL.Query())
+	if err != nil {
+		h.Logger.WithError(err).WithField("adId", ad.ID).Error("Invalid ad target URL")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid target URL"})
+		return
+	}
+
+	if !replayed {
+		if _, err := Enqueue(c.Request.Context(), h.Queue, h.Fallback, RetryableClick{Kind: KindClick, Event: event}, h.Logger); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to record click"})
+			return
+		}
+	}
+
+	h.Logger.WithFields(map[string]interface{}{
+		"adId":     ad.ID,
+		"clickId":  event.ID,
+		"replayed": replayed,
+	}).Info("Redirecting click")
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// ExpandTargetURL substitutes the {click_id}, {ad_id}, {campaign_id} and
+// {timestamp} macros in the ad's target URL and adds utm_* parameters.
+// utm_* values on the incoming request override the defaults, but never a
+// value the advertiser already put in the target URL. The result must be an
+// absolute http or https URL.
+func ExpandTargetURL(ad ads.Ad, clickID string, query url.Values) (string, error) {
+	macros := strings.NewReplacer(
+		"{click_id}", url.QueryEscape(clickID),
+		"{ad_id}", url.QueryEscape(ad.ID),
+		"{campaign_id}", url.QueryEscape(ad.CampaignID),
+		"{timestamp}", strconv.FormatInt(time.Now().Unix(), 10),
+	)
+
+	u, err := url.Parse(macros.Replace(ad.TargetURL))
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("target URL %q is not an absolute http(s) URL", ad.TargetURL)
+	}
+
+	utm := map[string]string{
+		"utm_source":   utmSource,
+		"utm_medium":   utmMedium,
+		"utm_campaign": ad.CampaignID,
+		"utm_content":  ad.ID,
+	}
+	for k, v := range query {
+		if strings.HasPrefix(k, "utm_") && len(v) > 0 {
+			utm[k] = v[0]
+		}
+	}
+
+	q := u.Query()
+	for k, v := range utm {
+		if v != "" && q.Get(k) == "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,395 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/scheduler"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the complete service configuration. Every setting has a default
+// and can be overridden, in increasing order of precedence, by the config
+// file, the environment variable named by its env tag and the command-line
+// flag named after its yaml path, e.g. --redis.addr. Fields tagged secret
+// are redacted by Redacted.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Queue     QueueConfig     `yaml:"queue"`
+	Worker    WorkerConfig    `yaml:"worker"`
+	Tracking  TrackingConfig  `yaml:"tracking"`
+	Fraud     FraudConfig     `yaml:"fraud"`
+	Selection SelectionConfig `yaml:"selection"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Health    HealthConfig    `yaml:"health"`
+	Ingest    IngestConfig    `yaml:"ingest"`
+	Leader    LeaderConfig    `yaml:"leader"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+}
+
+type ServerConfig struct {
+	Port            string        `yaml:"port" env:"PORT" usage:"HTTP listen port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"overall deadline of the shutdown sequence"`
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" usage:"how long /readyz reports not ready before the server stops accepting connections"`
+	AdminToken      string        `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true" usage:"bearer token for the /admin endpoints, which are disabled when empty"`
+	TrustedProxies  string        `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"comma-separated IPs or CIDRs of the proxies whose X-Forwarded-For gives the client IP; none when empty"`
+	PublicURL       string        `yaml:"public_url" env:"PUBLIC_URL" usage:"scheme and host clients reach the service at, used for the tracking URLs in VAST documents"`
+}
+
+type DatabaseConfig struct {
+	URL            string        `yaml:"url" env:"DATABASE_URL" secret:"true" usage:"Postgres connection URL"`
+	ConnectRetries int           `yaml:"connect_retries" env:"DB_CONNECT_RETRIES" usage:"connection attempts at startup"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" usage:"timeout of each connection attempt"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR" usage:"Redis address"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true" usage:"Redis password"`
+	DB       int    `yaml:"db" env:"REDIS_DB" usage:"Redis database number"`
+}
+
+type QueueConfig struct {
+	Backend        string        `yaml:"backend" env:"QUEUE_BACKEND" usage:"event queue: list, stream or memory"`
+	ReserveTimeout time.Duration `yaml:"reserve_timeout" env:"QUEUE_RESERVE_TIMEOUT" usage:"timeout of each reserve call"`
+	ClaimTimeout   time.Duration `yaml:"claim_timeout" env:"QUEUE_CLAIM_TIMEOUT" usage:"time after which events reserved by a worker that died are redelivered"`
+	FallbackFile   string        `yaml:"fallback_file" env:"FALLBACK_FILE" usage:"file events are written to while the queue is unreachable"`
+}
+
+type WorkerConfig struct {
+	Count                 int           `yaml:"count" env:"WORKER_COUNT" usage:"queue workers"`
+	MaxRetries            int           `yaml:"max_retries" env:"WORKER_MAX_RETRIES" usage:"attempts before an event is dead-lettered"`
+	RetryBackoff          time.Duration `yaml:"retry_backoff" env:"WORKER_RETRY_BACKOFF" usage:"pause after a failed event"`
+	SyncInterval          time.Duration `yaml:"sync_interval" env:"ANALYTICS_SYNC_INTERVAL" usage:"interval of the Redis to Postgres analytics sync"`
+	FallbackFlushInterval time.Duration `yaml:"fallback_flush_interval" env:"FALLBACK_FLUSH_INTERVAL" usage:"interval of the fallback file flush"`
+	MinCount              int           `yaml:"min_count" env:"WORKER_MIN_COUNT" usage:"fewest workers the autoscaler keeps"`
+	MaxCount              int           `yaml:"max_count" env:"WORKER_MAX_COUNT" usage:"most workers the autoscaler starts"`
+	ScaleInterval         time.Duration `yaml:"scale_interval" env:"WORKER_SCALE_INTERVAL" usage:"how often queue depth and latency are sampled for scaling"`
+	ScaleUpBacklog        int           `yaml:"scale_up_backlog" env:"WORKER_SCALE_UP_BACKLOG" usage:"queued events per worker above which the pool grows"`
+	ScaleDownBacklog      int           `yaml:"scale_down_backlog" env:"WORKER_SCALE_DOWN_BACKLOG" usage:"queued events per worker below which the pool shrinks"`
+	LatencyTarget         time.Duration `yaml:"latency_target" env:"WORKER_LATENCY_TARGET" usage:"mean processing time per event above which a backlogged pool grows"`
+	DedupTTL              time.Duration `yaml:"dedup_ttl" env:"WORKER_DEDUP_TTL" usage:"how long an event's analytics updates are remembered, so a redelivery is not counted again"`
+}
+
+type TrackingConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env:"TRACKING_TOKEN_TTL" usage:"lifetime of tracking tokens"`
+	Keys     string        `yaml:"keys" env:"TRACKING_KEYS" secret:"true" usage:"token signing keys, kid:secret,... (active key first)"`
+}
+
+type FraudConfig struct {
+	MaxClicksPerMinute int           `yaml:"max_clicks_per_minute" env:"FRAUD_MAX_CLICKS_PER_MINUTE" usage:"clicks per IP and ad per minute before the rate rule fires"`
+	MinClickDelay      time.Duration `yaml:"min_click_delay" env:"FRAUD_MIN_CLICK_DELAY" usage:"clicks sooner than this after the impression are suspicious"`
+	Threshold          float64       `yaml:"threshold" env:"FRAUD_THRESHOLD" usage:"score at which a click is invalid"`
+	DatacenterCIDRs    string        `yaml:"datacenter_cidrs" env:"FRAUD_DATACENTER_CIDRS" usage:"file of datacenter CIDR ranges"`
+}
+
+type SelectionConfig struct {
+	Strategy         string        `yaml:"strategy" env:"AD_SELECTION" usage:"ad selection: random or thompson"`
+	ExplorationFloor float64       `yaml:"exploration_floor" env:"BANDIT_EXPLORATION_FLOOR" usage:"minimum traffic share per creative under thompson"`
+	RefreshInterval  time.Duration `yaml:"refresh_interval" env:"BANDIT_REFRESH_INTERVAL" usage:"interval of the bandit posterior refresh"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" usage:"log level"`
+	File  string `yaml:"file" env:"LOG_FILE" usage:"log file, stdout if it cannot be opened"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" usage:"span exporter: none, stdout, file or otlp"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT" usage:"OTLP/HTTP collector URL, default http://localhost:4318"`
+	File        string  `yaml:"file" env:"TRACING_FILE" usage:"span file of the file exporter"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"share of new traces recorded"`
+}
+
+type HealthConfig struct {
+	MaxQueueDepth      int           `yaml:"max_queue_depth" env:"HEALTH_MAX_QUEUE_DEPTH" usage:"queued events above which /readyz reports not ready"`
+	MaxFallbackRecords int           `yaml:"max_fallback_records" env:"HEALTH_MAX_FALLBACK_RECORDS" usage:"fallback file events above which /readyz reports not ready"`
+	WorkerStallTimeout time.Duration `yaml:"worker_stall_timeout" env:"HEALTH_WORKER_STALL_TIMEOUT" usage:"silence after which a worker counts as stalled"`
+	CheckTimeout       time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" usage:"timeout of each dependency check"`
+}
+
+type IngestConfig struct {
+	QueueHighWater   int           `yaml:"queue_high_water" env:"INGEST_QUEUE_HIGH_WATER" usage:"queued events above which events outside priority_kinds are shed with 429"`
+	QueueHardLimit   int           `yaml:"queue_hard_limit" env:"INGEST_QUEUE_HARD_LIMIT" usage:"queued events above which every event is shed with 429"`
+	FallbackMaxBytes int           `yaml:"fallback_max_bytes" env:"INGEST_FALLBACK_MAX_BYTES" usage:"fallback file size above which every event is shed with 503"`
+	PriorityKinds    string        `yaml:"priority_kinds" env:"INGEST_PRIORITY_KINDS" usage:"event kinds kept until queue_hard_limit: click, impression, video, conversion"`
+	RetryAfter       time.Duration `yaml:"retry_after" env:"INGEST_RETRY_AFTER" usage:"Retry-After sent with shed events"`
+	SampleInterval   time.Duration `yaml:"sample_interval" env:"INGEST_SAMPLE_INTERVAL" usage:"how often queue depth and fallback size are sampled"`
+}
+
+type LeaderConfig struct {
+	Key           string        `yaml:"key" env:"LEADER_KEY" usage:"Redis key of the lease held by the replica running singleton jobs"`
+	Lease         time.Duration `yaml:"lease" env:"LEADER_LEASE" usage:"lease lifetime; a dead leader is replaced after at most this long"`
+	RenewInterval time.Duration `yaml:"renew_interval" env:"LEADER_RENEW_INTERVAL" usage:"how often the leader renews, and followers try to take, the lease"`
+}
+
+type SchedulerConfig struct {
+	Jitter     time.Duration `yaml:"jitter" env:"SCHEDULER_JITTER" usage:"maximum random delay added to each scheduled background job run"`
+	JobTimeout time.Duration `yaml:"job_timeout" env:"SCHEDULER_JOB_TIMEOUT" usage:"timeout of each background job run"`
+	History    int           `yaml:"history" env:"SCHEDULER_HISTORY" usage:"runs kept per background job for /admin/jobs"`
+
+	AnalyticsSync string `yaml:"analytics_sync" env:"SCHEDULER_ANALYTICS_SYNC" usage:"schedule of the analytics_sync job, e.g. @every 1m, @hourly or a cron expression; every worker.sync_interval when empty"`
+	FallbackFlush string `yaml:"fallback_flush" env:"SCHEDULER_FALLBACK_FLUSH" usage:"schedule of the fallback_flush job; every worker.fallback_flush_interval when empty"`
+	BanditRefresh string `yaml:"bandit_refresh" env:"SCHEDULER_BANDIT_REFRESH" usage:"schedule of the bandit_refresh job; every selection.refresh_interval when empty"`
+}
+
+// Kinds returns the priority event kinds.
+func (c IngestConfig) Kinds() []string {
+	return splitList(c.PriorityKinds)
+}
+
+// Proxies returns the trusted proxies.
+func (c ServerConfig) Proxies() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// splitList splits a comma-separated setting, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            "8080",
+			ShutdownTimeout: 10 * time.Second,
+			DrainDelay:      2 * time.Second,
+			PublicURL:       "http://localhost:8080",
+		},
+		Database: DatabaseConfig{
+			ConnectRetries: 10,
+			ConnectTimeout: 5 * time.Second,
+		},
+		Queue: QueueConfig{
+			Backend:        "list",
+			ReserveTimeout: 5 * time.Second,
+			ClaimTimeout:   5 * time.Minute,
+			FallbackFile:   "fallback_clicks.jsonl",
+		},
+		Worker: WorkerConfig{
+			Count:                 4,
+			MaxRetries:            3,
+			RetryBackoff:          2 * time.Second,
+			SyncInterval:          time.Minute,
+			FallbackFlushInterval: time.Minute,
+			MinCount:              1,
+			MaxCount:              16,
+			ScaleInterval:         10 * time.Second,
+			ScaleUpBacklog:        100,
+			ScaleDownBacklog:      10,
+			LatencyTarget:         500 * time.Millisecond,
+			DedupTTL:              24 * time.Hour,
+		},
+		Tracking: TrackingConfig{
+			TokenTTL: 2 * time.Hour,
+		},
+		Fraud: FraudConfig{
+			MaxClicksPerMinute: 5,
+			MinClickDelay:      time.Second,
+			Threshold:          0.5,
+			DatacenterCIDRs:    "config/datacenter_cidrs.txt",
+		},
+		Selection: SelectionConfig{
+			Strategy:         "random",
+			ExplorationFloor: 0.05,
+			RefreshInterval:  30 * time.Second,
+		},
+		Log: LogConfig{
+			Level: "info",
+			File:  "./internal/logs/app.log",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "./internal/logs/traces.jsonl",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			MaxQueueDepth:      100000,
+			MaxFallbackRecords: 10000,
+			WorkerStallTimeout: 5 * time.Minute,
+			CheckTimeout:       2 * time.Second,
+		},
+		Ingest: IngestConfig{
+			QueueHighWater:   500000,
+			QueueHardLimit:   1000000,
+			FallbackMaxBytes: 100 << 20,
+			PriorityKinds:    "click,conversion",
+			RetryAfter:       5 * time.Second,
+			SampleInterval:   time.Second,
+		},
+		Leader: LeaderConfig{
+			Key:           "video-ad-tracker:leader",
+			Lease:         15 * time.Second,
+			RenewInterval: 5 * time.Second,
+		},
+		Scheduler: SchedulerConfig{
+			Jitter:     5 * time.Second,
+			JobTimeout: 5 * time.Minute,
+			History:    20,
+		},
+	}
+}
+
+// Validate checks every setting and reports all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port: %q is not a valid port", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay: must not be negative")
+	for _, proxy := range c.Server.Proxies() {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies: %q is not an IP or CIDR", proxy)
+	}
+	public, err := url.Parse(c.Server.PublicURL)
+	check(err == nil && (public.Scheme == "http" || public.Scheme == "https") && public.Host != "",
+		"server.public_url: %q is not an absolute http(s) URL", c.Server.PublicURL)
+
+	check(c.Database.URL != "", "database.url: required (DATABASE_URL)")
+	check(c.Database.ConnectRetries >= 1, "database.connect_retries: must be at least 1")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout: must be positive")
+
+	check(c.Redis.Addr != "", "redis.addr: required (REDIS_ADDR)")
+	check(c.Redis.DB >= 0, "redis.db: must not be negative")
+
+	check(oneOf(c.Queue.Backend, "list", "stream", "memory"), "queue.backend: %q, expected list, stream or memory", c.Queue.Backend)
+	check(c.Queue.ReserveTimeout > 0, "queue.reserve_timeout: must be positive")
+	check(c.Queue.ClaimTimeout > 0, "queue.claim_timeout: must be positive")
+	check(c.Queue.FallbackFile != "", "queue.fallback_file: must be set")
+
+	check(c.Worker.Count >= 1, "worker.count: must be at least 1")
+	check(c.Worker.MaxRetries >= 1, "worker.max_retries: must be at least 1")
+	check(c.Worker.RetryBackoff >= 0, "worker.retry_backoff: must not be negative")
+	check(c.Worker.SyncInterval > 0, "worker.sync_interval: must be positive")
+	check(c.Worker.FallbackFlushInterval > 0, "worker.fallback_flush_interval: must be positive")
+	check(c.Worker.MinCount >= 1, "worker.min_count: must be at least 1")
+	check(c.Worker.MaxCount >= c.Worker.MinCount, "worker.max_count: must be at least worker.min_count")
+	check(c.Worker.Count >= c.Worker.MinCount && c.Worker.Count <= c.Worker.MaxCount, "worker.count: must be between worker.min_count and worker.max_count")
+	check(c.Worker.ScaleInterval > 0, "worker.scale_interval: must be positive")
+	check(c.Worker.ScaleDownBacklog >= 0 && c.Worker.ScaleDownBacklog < c.Worker.ScaleUpBacklog, "worker.scale_down_backlog: must be below worker.scale_up_backlog")
+	check(c.Worker.LatencyTarget > 0, "worker.latency_target: must be positive")
+	check(c.Worker.DedupTTL >= time.Second, "worker.dedup_ttl: must be at least 1s")
+
+	check(c.Tracking.TokenTTL > 0, "tracking.token_ttl: must be positive")
+	if c.Tracking.Keys != "" {
+		keys, err := signing.ParseKeys(c.Tracking.Keys)
+		check(err == nil, "tracking.keys: %v", err)
+		for _, k := range keys {
+			check(string(k.Secret) != placeholderSecret, "tracking.keys: key %q has the placeholder secret %q, set a random one", k.ID, placeholderSecret)
+		}
+	}
+
+	check(c.Fraud.MaxClicksPerMinute >= 1, "fraud.max_clicks_per_minute: must be at least 1")
+	check(c.Fraud.MinClickDelay >= 0, "fraud.min_click_delay: must not be negative")
+	check(c.Fraud.Threshold > 0, "fraud.threshold: must be positive")
+
+	check(oneOf(c.Selection.Strategy, "random", "thompson"), "selection.strategy: %q, expected random or thompson", c.Selection.Strategy)
+	check(c.Selection.ExplorationFloor >= 0 && c.Selection.ExplorationFloor <= 1, "selection.exploration_floor: must be between 0 and 1")
+	check(c.Selection.RefreshInterval > 0, "selection.refresh_interval: must be positive")
+
+	_, err = logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level: %q is not a log level", c.Log.Level)
+
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "file", "otlp"), "tracing.exporter: %q, expected none, stdout, file or otlp", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file: required by the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be between 0 and 1")
+
+	check(c.Health.MaxQueueDepth >= 1, "health.max_queue_depth: must be at least 1")
+	check(c.Health.MaxFallbackRecords >= 1, "health.max_fallback_records: must be at least 1")
+	check(c.Health.WorkerStallTimeout > c.Queue.ReserveTimeout, "health.worker_stall_timeout: must exceed queue.reserve_timeout")
+	check(c.Health.CheckTimeout > 0, "health.check_timeout: must be positive")
+
+	check(c.Ingest.QueueHighWater >= 1, "ingest.queue_high_water: must be at least 1")
+	check(c.Ingest.QueueHardLimit >= c.Ingest.QueueHighWater, "ingest.queue_hard_limit: must be at least ingest.queue_high_water")
+	check(c.Ingest.FallbackMaxBytes >= 1, "ingest.fallback_max_bytes: must be at least 1")
+	for _, kind := range c.Ingest.Kinds() {
+		check(oneOf(kind, "click", "impression", "video", "conversion"), "ingest.priority_kinds: unknown event kind %q", kind)
+	}
+	check(c.Ingest.RetryAfter >= time.Second, "ingest.retry_after: must be at least 1s")
+	check(c.Ingest.SampleInterval > 0, "ingest.sample_interval: must be positive")
+
+	check(c.Leader.Key != "", "leader.key: required")
+	check(c.Leader.RenewInterval > 0, "leader.renew_interval: must be positive")
+	check(c.Leader.Lease > c.Leader.RenewInterval, "leader.lease: must exceed leader.renew_interval")
+
+	check(c.Scheduler.Jitter >= 0, "scheduler.jitter: must not be negative")
+	check(c.Scheduler.JobTimeout > 0, "scheduler.job_timeout: must be positive")
+	check(c.Scheduler.History >= 1, "scheduler.history: must be at least 1")
+	if _, err := c.JobSchedules(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// SigningKeys returns the parsed tracking token keys, or nil if none are set.
+func (c *Config) SigningKeys() ([]signing.Key, error) {
+	if c.Tracking.Keys == "" {
+		return nil, nil
+	}
+	return signing.ParseKeys(c.Tracking.Keys)
+}
+
+// JobSchedules returns the schedule of each background job by name: the
+// job's scheduler setting when set, otherwise its interval setting.
+func (c *Config) JobSchedules() (map[string]scheduler.Schedule, error) {
+	jobs := []struct {
+		name  string
+		spec  string
+		every time.Duration
+	}{
+		{"analytics_sync", c.Scheduler.AnalyticsSync, c.Worker.SyncInterval},
+		{"fallback_flush", c.Scheduler.FallbackFlush, c.Worker.FallbackFlushInterval},
+		{"bandit_refresh", c.Scheduler.BanditRefresh, c.Selection.RefreshInterval},
+	}
+
+	schedules := make(map[string]scheduler.Schedule, len(jobs))
+	var errs []error
+	for _, job := range jobs {
+		if job.spec == "" {
+			schedules[job.name] = scheduler.Every(job.every)
+			continue
+		}
+		schedule, err := scheduler.Parse(job.spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheduler.%s: %w", job.name, err))
+			continue
+		}
+		schedules[job.name] = schedule
+	}
+	return schedules, errors.Join(errs...)
+}
+
+// placeholderSecret is the secret the example configuration shows. It is
+// rejected so that a copied example never signs real tokens.
+const placeholderSecret = "change-me"
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
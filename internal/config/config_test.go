@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+var required = map[string]string{
+	"DATABASE_URL": "postgres://user:hunter2@db/ads",
+	"REDIS_ADDR":   "redis:6379",
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, opts, err := Load(nil, env(required))
+	require.NoError(t, err)
+	assert.False(t, opts.PrintConfig)
+
+	want := Default()
+	want.Database.URL = required["DATABASE_URL"]
+	want.Redis.Addr = required["REDIS_ADDR"]
+	assert.Equal(t, want, *cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "worker:\n  count: 2\n  sync_interval: 30s\nfraud:\n  threshold: 0.7\nlog:\n  level: debug\n",
+		"config.toml": "[worker]\ncount = 2\nsync_interval = \"30s\"\n[fraud]\nthreshold = 0.7\n[log]\nlevel = \"debug\"\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			vars := map[string]string{"WORKER_COUNT": "6", "LOG_LEVEL": "warn"}
+			for k, v := range required {
+				vars[k] = v
+			}
+			args := []string{"--config", writeFile(t, name, content), "--log.level=error"}
+
+			cfg, _, err := Load(args, env(vars))
+			require.NoError(t, err)
+			assert.Equal(t, 30*time.Second, cfg.Worker.SyncInterval, "file overrides default")
+			assert.Equal(t, 0.7, cfg.Fraud.Threshold, "file overrides default")
+			assert.Equal(t, 6, cfg.Worker.Count, "env overrides file")
+			assert.Equal(t, "error", cfg.Log.Level, "flag overrides env")
+			assert.Equal(t, 3, cfg.Worker.MaxRetries, "default kept")
+		})
+	}
+}
+
+func TestLoad_ReportsAllErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", "worker:\n  count: 0\n  retries: 3\n")
+	vars := map[string]string{"REDIS_DB": "one", "QUEUE_BACKEND": "kafka", "TRUSTED_PROXIES": "10.0.0.0/8, proxy", "PUBLIC_URL": "ads.example", "TRACKING_KEYS": "k2:fresh,k1:change-me", "SCHEDULER_FALLBACK_FLUSH": "*/90 * * *"}
+
+	_, _, err := Load([]string{"--config", path, "--selection.exploration_floor=2"}, env(vars))
+	require.Error(t, err)
+	for _, msg := range []string{
+		"unknown setting worker.retries",
+		`REDIS_DB: "one" is not an integer`,
+		"database.url: required",
+		"redis.addr: required",
+		`queue.backend: "kafka"`,
+		`server.trusted_proxies: "proxy" is not an IP or CIDR`,
+		`server.public_url: "ads.example" is not an absolute http(s) URL`,
+		`tracking.keys: key "k1" has the placeholder secret "change-me"`,
+		"worker.count: must be at least 1",
+		"selection.exploration_floor: must be between 0 and 1",
+		`scheduler.fallback_flush: schedule "*/90 * * *": want 5 cron fields, got 4`,
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+}
+
+func TestJobSchedules(t *testing.T) {
+	cfg := Default()
+	cfg.Scheduler.BanditRefresh = "@daily"
+
+	schedules, err := cfg.JobSchedules()
+	require.NoError(t, err)
+	assert.Equal(t, "@every 1m0s", schedules["analytics_sync"].String())
+	assert.Equal(t, "@daily", schedules["bandit_refresh"].String())
+}
+
+func TestLoad_UnsupportedFile(t *testing.T) {
+	_, _, err := Load([]string{"--config", writeFile(t, "config.json", "{}")}, env(required))
+	assert.ErrorContains(t, err, "unsupported format")
+}
+
+func TestRedacted(t *testing.T) {
+	vars := map[string]string{"TRACKING_KEYS": "k1:topsecret", "REDIS_PASSWORD": "hunter3"}
+	for k, v := range required {
+		vars[k] = v
+	}
+	cfg, opts, err := Load([]string{"--print-config"}, env(vars))
+	require.NoError(t, err)
+	assert.True(t, opts.PrintConfig)
+
+	out := cfg.Redacted()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "hunter3")
+	assert.NotContains(t, out, "topsecret")
+	assert.Contains(t, out, `  url: "REDACTED"`)
+	assert.Contains(t, out, `  addr: "redis:6379"`)
+	assert.Contains(t, out, "  sync_interval: 1m0s")
+}
+
+func TestChanged(t *testing.T) {
+	old := Default()
+	next := Default()
+	next.Worker.Count = 8
+	next.Log.Level = "debug"
+	next.Redis.Addr = "other:6379"
+
+	changed := old.Changed(&next)
+	assert.Equal(t, []string{"redis.addr", "worker.count", "log.level"}, changed)
+	assert.True(t, Reloadable["worker.count"])
+	assert.False(t, Reloadable["redis.addr"])
+}
@@ -2,47 +2,53 @@ package config
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
 )
 
-var DB *pgxpool.Pool
-var logger = logs.Logger
-
-func InitDB() {
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		logger.Fatal("DATABASE_URL not set")
+// ConnectDB opens a connection pool to the database, retrying while it
+// comes up.
+func ConnectDB(ctx context.Context, cfg DatabaseConfig, logger logrus.FieldLogger) (*pgxpool.Pool, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("DATABASE_URL not set")
 	}
 
-	var pool *pgxpool.Pool
 	var err error
 
-	maxRetries := 10
-	for i := 0; i < maxRetries; i++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		pool, err = pgxpool.New(ctx, dbURL)
-		if err != nil {
-			logger.WithError(err).Errorf("Attempt %d: Failed to create pool", i+1)
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
-		}
-
-		err = pool.Ping(ctx)
+	for i := 0; i < cfg.ConnectRetries; i++ {
+		var pool *pgxpool.Pool
+		pool, err = connect(ctx, cfg.URL, cfg.ConnectTimeout)
 		if err == nil {
-			DB = pool
 			logger.Info("Connected to database")
-			return
+			return pool, nil
 		}
 
-		logger.WithError(err).Errorf("Attempt %d: Database ping failed", i+1)
-		time.Sleep(time.Second * time.Duration(i+1))
+		logger.WithError(err).Errorf("Attempt %d: Database connection failed", i+1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second * time.Duration(i+1)):
+		}
 	}
 
-	logger.WithError(err).Fatal("Exceeded max retries: Unable to connect to database")
+	return nil, fmt.Errorf("exceeded max retries: unable to connect to database: %w", err)
+}
+
+func connect(ctx context.Context, dbURL string, timeout time.Duration) (*pgxpool.Pool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
 }
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the config file when --config is not given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Options are the command-line switches that are not settings themselves.
+type Options struct {
+	// File is the YAML (.yaml, .yml) or TOML (.toml) config file, if any.
+	File string
+	// PrintConfig asks for the effective configuration to be printed.
+	PrintConfig bool
+}
+
+// field is one setting, addressed by its dotted yaml path.
+type field struct {
+	path   string
+	env    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// fields lists the settings of c in declaration order.
+func fields(c *Config) []field {
+	var out []field
+	cv := reflect.ValueOf(c).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		section := cv.Type().Field(i)
+		sv := cv.Field(i)
+		for j := 0; j < sv.NumField(); j++ {
+			f := sv.Type().Field(j)
+			out = append(out, field{
+				path:   section.Tag.Get("yaml") + "." + f.Tag.Get("yaml"),
+				env:    f.Tag.Get("env"),
+				usage:  f.Tag.Get("usage"),
+				secret: f.Tag.Get("secret") == "true",
+				value:  sv.Field(j),
+			})
+		}
+	}
+	return out
+}
+
+// set parses s into the setting.
+func (f field) set(s string) error {
+	s = strings.TrimSpace(s)
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		f.value.SetInt(int64(n))
+	case float64:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		f.value.SetFloat(v)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+		f.value.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	if d, ok := f.value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment (read through lookupEnv) and args, later sources taking
+// precedence. Malformed values, which leave the setting unchanged, and
+// failed validation are reported together in the returned error, alongside
+// the configuration as loaded; an unreadable config file or bad flags fail
+// immediately.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, Options, error) {
+	cfg := Default()
+	settings := fields(&cfg)
+
+	var opts Options
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&opts.File, "config", "", "YAML or TOML config file (default $"+ConfigFileEnv+")")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration, secrets redacted, and exit")
+	flagValues := make(map[string]*string, len(settings))
+	for _, f := range settings {
+		flagValues[f.path] = fs.String(f.path, "", f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+	if opts.File == "" {
+		opts.File, _ = lookupEnv(ConfigFileEnv)
+	}
+
+	var errs []error
+	if opts.File != "" {
+		values, err := readFile(opts.File)
+		if err != nil {
+			return nil, opts, err
+		}
+		known := make(map[string]bool, len(settings))
+		for _, f := range settings {
+			known[f.path] = true
+			if v, ok := values[f.path]; ok {
+				if err := f.set(v); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s: %w", opts.File, f.path, err))
+				}
+			}
+		}
+		for path := range values {
+			if !known[path] {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %s", opts.File, path))
+			}
+		}
+	}
+
+	for _, f := range settings {
+		if v, ok := lookupEnv(f.env); ok && v != "" {
+			if err := f.set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+
+	fs.Visit(func(fl *flag.Flag) {
+		for _, f := range settings {
+			if f.path == fl.Name {
+				if err := f.set(*flagValues[f.path]); err != nil {
+					errs = append(errs, fmt.Errorf("--%s: %w", f.path, err))
+				}
+			}
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return &cfg, opts, errors.Join(errs...)
+}
+
+// readFile flattens a YAML or TOML file into dotted setting paths.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	doc := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	for section, v := range doc {
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			values[section] = fmt.Sprint(v)
+			continue
+		}
+		for key, value := range entries {
+			values[section+"."+key] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+// Reloadable lists the settings a running server applies on reload; the
+// rest take effect on restart.
+var Reloadable = map[string]bool{
+	"worker.count":                   true,
+	"worker.max_retries":             true,
+	"worker.retry_backoff":           true,
+	"worker.sync_interval":           true,
+	"worker.fallback_flush_interval": true,
+	"worker.min_count":               true,
+	"worker.max_count":               true,
+	"worker.scale_interval":          true,
+	"worker.scale_up_backlog":        true,
+	"worker.scale_down_backlog":      true,
+	"worker.latency_target":          true,
+	"worker.dedup_ttl":               true,
+	"fraud.max_clicks_per_minute":    true,
+	"fraud.min_click_delay":          true,
+	"fraud.threshold":                true,
+	"log.level":                      true,
+	"health.max_queue_depth":         true,
+	"health.max_fallback_records":    true,
+	"health.worker_stall_timeout":    true,
+	"health.check_timeout":           true,
+	"ingest.queue_high_water":        true,
+	"ingest.queue_hard_limit":        true,
+	"ingest.fallback_max_bytes":      true,
+	"ingest.priority_kinds":          true,
+	"ingest.retry_after":             true,
+	"ingest.sample_interval":         true,
+	"scheduler.analytics_sync":       true,
+	"scheduler.fallback_flush":       true,
+	"scheduler.bandit_refresh":       true,
+}
+
+// Changed returns the paths of the settings that differ between c and other.
+func (c *Config) Changed(other *Config) []string {
+	var paths []string
+	theirs := fields(other)
+	for i, f := range fields(c) {
+		if f.value.Interface() != theirs[i].value.Interface() {
+			paths = append(paths, f.path)
+		}
+	}
+	return paths
+}
+
+// Redacted returns the configuration as YAML with secrets masked.
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	section := ""
+	for _, f := range fields(c) {
+		name, key, _ := strings.Cut(f.path, ".")
+		if name != section {
+			fmt.Fprintf(&b, "%s:\n", name)
+			section = name
+		}
+		value := f.String()
+		if f.secret && value != "" {
+			value = "REDACTED"
+		}
+		if _, isString := f.value.Interface().(string); isString {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", key, value)
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package conversions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Attribution models recorded on conversions.attribution.
+const (
+	LastClick    = "last_click"
+	LastView     = "last_view"
+	Unattributed = "unattributed"
+)
+
+// orderNamespace derives conversion IDs from advertiser order IDs, so a
+// postback that is sent twice is stored once.
+var orderNamespace = uuid.MustParse("6f1c2b9e-6b1a-4c57-9d0e-3a4f8e2d7c10")
+
+// Attribution is the ad (and the click or impression) a conversion is credited to.
+type Attribution struct {
+	Model        string
+	AdID         string
+	CampaignID   string
+	ClickID      string
+	ImpressionID string
+	ViewerID     string
+}
+
+// NewID returns the conversion ID for orderID, or a random one when the
+// advertiser did not send an order ID.
+func NewID(orderID string) string {
+	if orderID == "" {
+		return uuid.New().String()
+	}
+	return uuid.NewSHA1(orderNamespace, []byte(orderID)).String()
+}
+
+// Windows apply per campaign; ads without a campaign fall back to the
+// column defaults.
+const (
+	clickColumns = `SELECT c.id::text, c.ad_id::text, COALESCE(a.campaign_id::text, ''),
+	                       COALESCE(c.impression_id::text, ''), COALESCE(c.viewer_id, '')
+	                FROM click_events c
+	                JOIN ads a ON a.id = c.ad_id
+	                LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+	                WHERE c.is_valid AND c.timestamp <= $2
+	                  AND c.timestamp >= $2 - COALESCE(cp.click_window, INTERVAL '7 days')`
+
+	lastViewQuery = `SELECT i.id::text, i.ad_id::text, COALESCE(a.campaign_id::text, ''), i.viewer_id
+	                 FROM impressions i
+	                 JOIN ads a ON a.id = i.ad_id
+	                 LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+	                 WHERE i.viewer_id = $1 AND i.timestamp <= $2
+	                   AND i.timestamp >= $2 - COALESCE(cp.view_window, INTERVAL '1 day')
+	                 ORDER BY i.timestamp DESC LIMIT 1`
+)
+
+// Attribute credits conv to an ad. A named click inside its campaign's click
+// window wins; otherwise the viewer's most recent valid click inside its
+// window (last click), then their most recent impression inside the view
+// window (last view). Invalid clicks never receive credit.
+func Attribute(ctx context.Context, db *pgxpool.Pool, conv clicks.ConversionEvent) (Attribution, error) {
+	if conv.ClickID != "" {
+		attr, err := scanClick(db.QueryRow(ctx, clickColumns+` AND c.id = $1`, conv.ClickID, conv.Timestamp))
+		if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+			return attr, err
+		}
+	}
+
+	if conv.ViewerID != "" {
+		attr, err := scanClick(db.QueryRow(ctx,
+			clickColumns+` AND c.viewer_id = $1 ORDER BY c.timestamp DESC LIMIT 1`, conv.ViewerID, conv.Timestamp))
+		if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+			return attr, err
+		}
+
+		attr = Attribution{Model: LastView}
+		err = db.QueryRow(ctx, lastViewQuery, conv.ViewerID, conv.Timestamp).
+			Scan(&attr.ImpressionID, &attr.AdID, &attr.CampaignID, &attr.ViewerID)
+		if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+			return attr, err
+		}
+	}
+
+	return Attribution{Model: Unattributed, ViewerID: conv.ViewerID}, nil
+}
+
+func scanClick(row pgx.Row) (Attribution, error) {
+	attr := Attribution{Model: LastClick}
+	err := row.Scan(&attr.ClickID, &attr.AdID, &attr.CampaignID, &attr.ImpressionID, &attr.ViewerID)
+	return attr, err
+}
@@ -0,0 +1,29 @@
+package conversions
+
+import (
+	"context"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InsertConversion stores conv with its attribution. inserted is false when
+// the conversion was already recorded, e.g. a repeated postback for the same order.
+func InsertConversion(ctx context.Context, db *pgxpool.Pool, conv clicks.ConversionEvent, attr Attribution) (inserted bool, err error) {
+	viewerID := attr.ViewerID
+	if viewerID == "" {
+		viewerID = conv.ViewerID
+	}
+	tag, err := db.Exec(ctx,
+		`INSERT INTO conversions (id, order_id, click_id, impression_id, ad_id, campaign_id,
+		                          viewer_id, attribution, value, timestamp, ip_address)
+		 VALUES ($1, NULLIF($2, ''), NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid,
+		         NULLIF($6, '')::uuid, NULLIF($7, ''), $8, $9, $10, $11)
+		 ON CONFLICT (id) DO NOTHING;`,
+		conv.ID, conv.OrderID, attr.ClickID, attr.ImpressionID, attr.AdID, attr.CampaignID,
+		viewerID, attr.Model, conv.Value, conv.Timestamp, conv.IPAddress)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,43 @@
+package events
+
+// Video playback and interaction events, named as in VAST tracking.
+const (
+	VideoStart         = "start"
+	VideoFirstQuartile = "firstQuartile"
+	VideoMidpoint      = "midpoint"
+	VideoThirdQuartile = "thirdQuartile"
+	VideoComplete      = "complete"
+	VideoSkip          = "skip"
+	VideoPause         = "pause"
+	VideoMute          = "mute"
+	VideoFullscreen    = "fullscreen"
+)
+
+// VideoEventTypes lists every supported video event in playback order.
+var VideoEventTypes = []string{
+	VideoStart,
+	VideoFirstQuartile,
+	VideoMidpoint,
+	VideoThirdQuartile,
+	VideoComplete,
+	VideoSkip,
+	VideoPause,
+	VideoMute,
+	VideoFullscreen,
+}
+
+// IsVideoEvent reports whether name is a supported video event type.
+func IsVideoEvent(name string) bool {
+	for _, t := range VideoEventTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRepeatable reports whether a video event may legitimately fire more than
+// once for the same ad view.
+func IsRepeatable(name string) bool {
+	return name == VideoPause || name == VideoMute || name == VideoFullscreen
+}
@@ -0,0 +1,61 @@
+package experiments
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const selectExperiments = `SELECT e.id::text, COALESCE(e.campaign_id::text, ''), e.name, a.name, a.ad_id::text, a.weight
+                           FROM experiments e
+                           JOIN experiment_arms a ON a.experiment_id = e.id`
+
+// Running loads the experiments currently splitting traffic.
+func Running(ctx context.Context, db *pgxpool.Pool) ([]Experiment, error) {
+	rows, err := db.Query(ctx, selectExperiments+`
+		WHERE e.started_at <= NOW() AND (e.ended_at IS NULL OR e.ended_at > NOW())
+		ORDER BY e.started_at, e.id, a.name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanExperiments(rows)
+}
+
+// GetExperiment loads one experiment, running or not. It returns
+// pgx.ErrNoRows when the experiment does not exist or has no arms.
+func GetExperiment(ctx context.Context, db *pgxpool.Pool, id string) (Experiment, error) {
+	rows, err := db.Query(ctx, selectExperiments+` WHERE e.id = $1 ORDER BY a.name`, id)
+	if err != nil {
+		return Experiment{}, err
+	}
+	exps, err := scanExperiments(rows)
+	if err != nil {
+		return Experiment{}, err
+	}
+	if len(exps) == 0 {
+		return Experiment{}, pgx.ErrNoRows
+	}
+	return exps[0], nil
+}
+
+// scanExperiments groups one-row-per-arm results, which arrive ordered by experiment.
+func scanExperiments(rows pgx.Rows) ([]Experiment, error) {
+	defer rows.Close()
+
+	var exps []Experiment
+	for rows.Next() {
+		var exp Experiment
+		var arm Arm
+		if err := rows.Scan(&exp.ID, &exp.CampaignID, &exp.Name, &arm.Name, &arm.AdID, &arm.Weight); err != nil {
+			return nil, err
+		}
+		if n := len(exps); n > 0 && exps[n-1].ID == exp.ID {
+			exps[n-1].Arms = append(exps[n-1].Arms, arm)
+			continue
+		}
+		exp.Arms = []Arm{arm}
+		exps = append(exps, exp)
+	}
+	return exps, rows.Err()
+}
@@ -0,0 +1,56 @@
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Experiment splits a campaign's traffic between creatives. Each arm serves
+// one ad; the first arm (by name) is the baseline the others are tested against.
+type Experiment struct {
+	ID         string `json:"id"`
+	CampaignID string `json:"campaignId"`
+	Name       string `json:"name"`
+	Arms       []Arm  `json:"arms"`
+}
+
+type Arm struct {
+	Name   string `json:"name"`
+	AdID   string `json:"adId"`
+	Weight int    `json:"weight"`
+}
+
+// Assign returns the arm viewerID is in. The split is a hash of the
+// experiment and viewer IDs, so a viewer sees the same arm on every request
+// and on every replica, in proportion to the arm weights.
+func (e Experiment) Assign(viewerID string) Arm {
+	total := 0
+	for _, arm := range e.Arms {
+		total += arm.Weight
+	}
+	if total <= 0 {
+		return Arm{}
+	}
+
+	sum := sha256.Sum256([]byte(e.ID + ":" + viewerID))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, arm := range e.Arms {
+		if bucket < arm.Weight {
+			return arm
+		}
+		bucket -= arm.Weight
+	}
+	return e.Arms[len(e.Arms)-1]
+}
+
+// ForAd returns the experiment one of whose arms serves adID.
+func ForAd(exps []Experiment, adID string) (Experiment, bool) {
+	for _, exp := range exps {
+		for _, arm := range exp.Arms {
+			if arm.AdID == adID {
+				return exp, true
+			}
+		}
+	}
+	return Experiment{}, false
+}
@@ -0,0 +1,77 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testExperiment = Experiment{
+	ID:   "eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee",
+	Name: "creative test",
+	Arms: []Arm{
+		{Name: "control", AdID: "ad-a", Weight: 3},
+		{Name: "variant", AdID: "ad-b", Weight: 1},
+	},
+}
+
+func TestAssign_DeterministicAndWeighted(t *testing.T) {
+	assert.Equal(t, testExperiment.Assign("viewer-1"), testExperiment.Assign("viewer-1"))
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[testExperiment.Assign(fmt.Sprintf("viewer-%d", i)).Name]++
+	}
+	assert.InDelta(t, 7500, counts["control"], 300)
+	assert.InDelta(t, 2500, counts["variant"], 300)
+}
+
+func TestForAd(t *testing.T) {
+	exp, ok := ForAd([]Experiment{testExperiment}, "ad-b")
+	require.True(t, ok)
+	assert.Equal(t, testExperiment.ID, exp.ID)
+
+	_, ok = ForAd([]Experiment{testExperiment}, "ad-c")
+	assert.False(t, ok)
+}
+
+func TestWilson(t *testing.T) {
+	low, high := wilson(50, 1000)
+	assert.InDelta(t, 0.0381, low, 1e-4)
+	assert.InDelta(t, 0.0653, high, 1e-4)
+
+	low, high = wilson(0, 0)
+	assert.Zero(t, low)
+	assert.Zero(t, high)
+}
+
+func TestAnalyze(t *testing.T) {
+	report := Analyze(testExperiment, map[string]ArmCounts{
+		"control": {Impressions: 10000, Clicks: 500},
+		"variant": {Impressions: 10000, Clicks: 600},
+	})
+
+	require.Len(t, report.Arms, 2)
+	assert.Equal(t, 0.05, report.Arms[0].CTR)
+	assert.Less(t, report.Arms[0].CILow, 0.05)
+	assert.Greater(t, report.Arms[0].CIHigh, 0.05)
+
+	require.Len(t, report.Tests, 1)
+	test := report.Tests[0]
+	assert.Equal(t, "variant", test.Arm)
+	assert.Equal(t, "control", test.Baseline)
+	assert.InDelta(t, 0.2, test.Lift, 1e-9)
+	assert.InDelta(t, 3.10, test.Z, 0.01)
+	assert.InDelta(t, 0.0019, test.PValue, 0.0002)
+	assert.True(t, test.Significant)
+}
+
+func TestAnalyze_NoData(t *testing.T) {
+	report := Analyze(testExperiment, nil)
+
+	require.Len(t, report.Tests, 1)
+	assert.Equal(t, 1.0, report.Tests[0].PValue)
+	assert.False(t, report.Tests[0].Significant)
+}
@@ -0,0 +1,50 @@
+package experiments
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// GetExperimentAnalyticsHandler reports per-arm CTR with confidence
+// intervals and significance tests for the experiment named by :id.
+func GetExperimentAnalyticsHandler(db *pgxpool.Pool, store analytics.AnalyticsStore, logger logrus.FieldLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.WithField("path", "/experiments/:id/analytics")
+
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid experiment id"})
+			return
+		}
+
+		exp, err := GetExperiment(c.Request.Context(), db, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown experiment"})
+			return
+		}
+		if err != nil {
+			logger.WithError(err).WithField("experimentId", id).Error("Failed to load experiment")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load experiment"})
+			return
+		}
+
+		counts := make(map[string]ArmCounts, len(exp.Arms))
+		for _, arm := range exp.Arms {
+			impressions, clicks, err := store.GetArmCounts(c.Request.Context(), exp.ID, arm.Name)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch analytics"})
+				return
+			}
+			counts[arm.Name] = ArmCounts{Impressions: impressions, Clicks: clicks}
+		}
+
+		c.JSON(http.StatusOK, Analyze(exp, counts))
+	}
+}
@@ -0,0 +1,112 @@
+package experiments
+
+import "math"
+
+// z95 is the two-sided 95% critical value of the standard normal distribution.
+const z95 = 1.959964
+
+// Significance is the p-value below which an arm's difference from the baseline is reported as significant.
+const Significance = 0.05
+
+// ArmCounts are an arm's impressions and valid clicks.
+type ArmCounts struct {
+	Impressions int
+	Clicks      int
+}
+
+// ArmReport is an arm's CTR with its 95% Wilson score interval.
+type ArmReport struct {
+	Arm         string  `json:"arm"`
+	AdID        string  `json:"adId"`
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+	CILow       float64 `json:"ciLow"`
+	CIHigh      float64 `json:"ciHigh"`
+}
+
+// Comparison is a two-proportion z-test of an arm's CTR against the baseline.
+type Comparison struct {
+	Arm         string  `json:"arm"`
+	Baseline    string  `json:"baseline"`
+	Lift        float64 `json:"lift"`
+	Z           float64 `json:"z"`
+	PValue      float64 `json:"pValue"`
+	Significant bool    `json:"significant"`
+}
+
+type Report struct {
+	ExperimentID string       `json:"experimentId"`
+	Name         string       `json:"name"`
+	Arms         []ArmReport  `json:"arms"`
+	Tests        []Comparison `json:"tests"`
+}
+
+// Analyze reports per-arm CTR and tests every arm against the first.
+func Analyze(exp Experiment, counts map[string]ArmCounts) Report {
+	report := Report{ExperimentID: exp.ID, Name: exp.Name, Arms: []ArmReport{}, Tests: []Comparison{}}
+	for _, arm := range exp.Arms {
+		n := counts[arm.Name]
+		low, high := wilson(n.Clicks, n.Impressions)
+		report.Arms = append(report.Arms, ArmReport{
+			Arm:         arm.Name,
+			AdID:        arm.AdID,
+			Impressions: n.Impressions,
+			Clicks:      n.Clicks,
+			CTR:         ctr(n),
+			CILow:       low,
+			CIHigh:      high,
+		})
+	}
+
+	if len(exp.Arms) < 2 {
+		return report
+	}
+	baseline := exp.Arms[0].Name
+	for _, arm := range exp.Arms[1:] {
+		a, b := counts[baseline], counts[arm.Name]
+		z, p := zTest(a, b)
+		cmp := Comparison{Arm: arm.Name, Baseline: baseline, Z: z, PValue: p, Significant: p < Significance}
+		if base := ctr(a); base > 0 {
+			cmp.Lift = ctr(b)/base - 1
+		}
+		report.Tests = append(report.Tests, cmp)
+	}
+	return report
+}
+
+func ctr(n ArmCounts) float64 {
+	if n.Impressions == 0 {
+		return 0
+	}
+	return float64(n.Clicks) / float64(n.Impressions)
+}
+
+// wilson returns the 95% Wilson score interval for clicks out of
+// impressions, which stays inside [0, 1] even for small samples.
+func wilson(clicks, impressions int) (low, high float64) {
+	if impressions == 0 {
+		return 0, 0
+	}
+	n := float64(impressions)
+	p := float64(clicks) / n
+	z2 := z95 * z95
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := z95 * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// zTest is the pooled two-proportion z-test of b's CTR against a's, with
+// a two-sided p-value. Without data in both arms it reports no difference.
+func zTest(a, b ArmCounts) (z, p float64) {
+	if a.Impressions == 0 || b.Impressions == 0 {
+		return 0, 1
+	}
+	pooled := float64(a.Clicks+b.Clicks) / float64(a.Impressions+b.Impressions)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(a.Impressions) + 1/float64(b.Impressions)))
+	if se == 0 {
+		return 0, 1
+	}
+	z = (ctr(b) - ctr(a)) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
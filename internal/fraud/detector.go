@@ -0,0 +1,212 @@
+package fraud
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons recorded on click_events.fraud_reasons.
+const (
+	ReasonRateLimit    = "rate_limit"
+	ReasonNoImpression = "no_impression"
+	ReasonFastClick    = "fast_click"
+	ReasonDatacenterIP = "datacenter_ip"
+	ReasonBotUserAgent = "bot_user_agent"
+)
+
+// Weights are summed per matched rule; a click whose score reaches the
+// threshold is invalid and excluded from billable counts. Rate limiting and
+// bot user agents are hard signals that reach the default threshold alone.
+// A missing impression, a fast click and a datacenter IP, which real viewers
+// behind a VPN or relay also show, are soft signals: each stays below the
+// default threshold and it takes two of them to invalidate a click.
+var weights = map[string]float64{
+	ReasonRateLimit:    0.9,
+	ReasonNoImpression: 0.3,
+	ReasonFastClick:    0.3,
+	ReasonDatacenterIP: 0.3,
+	ReasonBotUserAgent: 1.0,
+}
+
+var botUserAgents = []string{
+	"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests",
+	"python-urllib", "go-http-client", "java/", "okhttp", "scrapy",
+	"headless", "phantomjs", "selenium", "puppeteer",
+}
+
+type Config struct {
+	MaxClicksPerMinute int
+	MinClickDelay      time.Duration
+	Threshold          float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxClicksPerMinute: 5,
+		MinClickDelay:      time.Second,
+		Threshold:          0.5,
+	}
+}
+
+// Result is the outcome of scoring a single click.
+type Result struct {
+	Score   float64
+	Reasons []string
+	Valid   bool
+}
+
+type Detector struct {
+	rdb         *redis.Client
+	mu          sync.RWMutex
+	cfg         Config
+	datacenters []*net.IPNet
+	metrics     *Metrics
+	logger      logrus.FieldLogger
+}
+
+func NewDetector(rdb *redis.Client, cfg Config, datacenters []*net.IPNet, metrics *Metrics, logger logrus.FieldLogger) *Detector {
+	return &Detector{rdb: rdb, cfg: cfg, datacenters: datacenters, metrics: metrics, logger: logger}
+}
+
+// SetConfig replaces the scoring thresholds; clicks scored afterwards use them.
+func (d *Detector) SetConfig(cfg Config) {
+	d.mu.Lock()
+	d.cfg = cfg
+	d.mu.Unlock()
+}
+
+func (d *Detector) config() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.cfg
+}
+
+// LoadCIDRs reads one CIDR per line, ignoring blank lines and # comments.
+func LoadCIDRs(path string) ([]*net.IPNet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nets []*net.IPNet
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, scanner.Err()
+}
+
+// Score applies every rule to click. imp is the impression the click was
+// attributed to, or nil when its impression ID is unknown or expired. Rules
+// that cannot be evaluated because Redis is unavailable are skipped rather
+// than counted against the click. Scoring is idempotent, so a redelivered
+// click gets the same result; it records no metrics, see Record.
+func (d *Detector) Score(ctx context.Context, click clicks.ClickEvent, imp *clicks.ImpressionEvent) Result {
+	cfg := d.config()
+	var reasons []string
+
+	if isBotUserAgent(click.UserAgent) {
+		reasons = append(reasons, ReasonBotUserAgent)
+	}
+	if d.isDatacenterIP(click.IPAddress) {
+		reasons = append(reasons, ReasonDatacenterIP)
+	}
+
+	if over, err := d.overRateLimit(ctx, click, cfg.MaxClicksPerMinute); err != nil {
+		d.logger.WithError(err).WithField("adId", click.AdID).Warn("Fraud rate check failed")
+	} else if over {
+		reasons = append(reasons, ReasonRateLimit)
+	}
+
+	switch {
+	case imp == nil || imp.AdID != click.AdID:
+		reasons = append(reasons, ReasonNoImpression)
+	case click.Timestamp.Sub(imp.Timestamp) < cfg.MinClickDelay:
+		reasons = append(reasons, ReasonFastClick)
+	}
+
+	var score float64
+	for _, r := range reasons {
+		score += weights[r]
+	}
+	if score > 1 {
+		score = 1
+	}
+	return Result{Score: score, Reasons: reasons, Valid: score < cfg.Threshold}
+}
+
+// Record counts a scored click in the fraud metrics. Callers record each
+// click once, after it has been applied, so redeliveries are not counted.
+func (d *Detector) Record(r Result) {
+	for _, reason := range r.Reasons {
+		d.metrics.clicksFlagged.WithLabelValues(reason).Inc()
+	}
+	if !r.Valid {
+		d.metrics.clicksInvalid.Inc()
+	}
+}
+
+// overRateLimit tracks click IDs per ad, IP and minute in a set so that
+// redelivering the same click does not inflate the count.
+func (d *Detector) overRateLimit(ctx context.Context, click clicks.ClickEvent, limit int) (bool, error) {
+	minute := strconv.FormatInt(click.Timestamp.Unix()/60, 10)
+	key := "fraud:rate:" + click.AdID + ":" + click.IPAddress + ":" + minute
+
+	pipe := d.rdb.TxPipeline()
+	pipe.SAdd(ctx, key, click.ID)
+	pipe.Expire(ctx, key, 2*time.Minute)
+	card := pipe.SCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return card.Val() > int64(limit), nil
+}
+
+func (d *Detector) isDatacenterIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range d.datacenters {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBotUserAgent(ua string) bool {
+	if strings.TrimSpace(ua) == "" {
+		return true
+	}
+	ua = strings.ToLower(ua)
+	for _, pattern := range botUserAgents {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package fraud
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const browserUA = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36"
+
+func newTestDetector(t *testing.T, datacenters []*net.IPNet) *Detector {
+	s := miniredis.RunT(t)
+	return NewDetector(redis.NewClient(&redis.Options{Addr: s.Addr()}), DefaultConfig(), datacenters, NewMetrics(), logs.Discard())
+}
+
+func click(id string, at time.Time) clicks.ClickEvent {
+	return clicks.ClickEvent{ID: id, AdID: "ad-1", IPAddress: "10.0.0.1", UserAgent: browserUA, Timestamp: at}
+}
+
+func impression(at time.Time) *clicks.ImpressionEvent {
+	return &clicks.ImpressionEvent{ID: "imp-1", AdID: "ad-1", IPAddress: "10.0.0.1", Timestamp: at}
+}
+
+func TestScore_ValidClickAfterImpression(t *testing.T) {
+	d := newTestDetector(t, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	result := d.Score(ctx, click("c1", now), impression(now.Add(-10*time.Second)))
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Reasons)
+	assert.Zero(t, result.Score)
+}
+
+func testDatacenters(t *testing.T) []*net.IPNet {
+	nets, err := LoadCIDRs(filepath.Join("testdata", "datacenter_cidrs.txt"))
+	require.NoError(t, err)
+	return nets
+}
+
+func TestScore_Rules(t *testing.T) {
+	d := newTestDetector(t, testDatacenters(t))
+	ctx := context.Background()
+	now := time.Now()
+
+	noImpression := d.Score(ctx, click("c1", now), nil)
+	assert.Equal(t, []string{ReasonNoImpression}, noImpression.Reasons)
+
+	otherAd := impression(now.Add(-time.Minute))
+	otherAd.AdID = "ad-2"
+	assert.Equal(t, []string{ReasonNoImpression}, d.Score(ctx, click("c5", now), otherAd).Reasons)
+
+	fast := d.Score(ctx, click("c2", now.Add(200*time.Millisecond)), impression(now))
+	assert.Equal(t, []string{ReasonFastClick}, fast.Reasons)
+
+	bot := click("c3", now.Add(5*time.Second))
+	bot.UserAgent = "curl/8.5.0"
+	assert.Contains(t, d.Score(ctx, bot, impression(now)).Reasons, ReasonBotUserAgent)
+
+	datacenter := click("c4", now)
+	datacenter.IPAddress = "203.0.113.9"
+	assert.Contains(t, d.Score(ctx, datacenter, impression(now.Add(-time.Minute))).Reasons, ReasonDatacenterIP)
+}
+
+func TestScore_SoftSignals(t *testing.T) {
+	d := newTestDetector(t, testDatacenters(t))
+	ctx := context.Background()
+	now := time.Now()
+
+	noImpression := d.Score(ctx, click("c1", now), nil)
+	assert.True(t, noImpression.Valid, "a missing impression alone is not enough")
+	assert.Less(t, noImpression.Score, d.cfg.Threshold)
+
+	fast := d.Score(ctx, click("c2", now.Add(200*time.Millisecond)), impression(now))
+	assert.True(t, fast.Valid, "a fast click alone is not enough")
+
+	datacenter := click("c3", now)
+	datacenter.IPAddress = "203.0.113.9"
+	assert.True(t, d.Score(ctx, datacenter, impression(now.Add(-time.Minute))).Valid, "a datacenter IP alone is not enough")
+
+	datacenter.ID = "c4"
+	both := d.Score(ctx, datacenter, nil)
+	assert.ElementsMatch(t, []string{ReasonDatacenterIP, ReasonNoImpression}, both.Reasons)
+	assert.False(t, both.Valid, "two soft signals are")
+
+	bot := click("c5", now.Add(5*time.Second))
+	bot.UserAgent = "curl/8.5.0"
+	assert.False(t, d.Score(ctx, bot, impression(now)).Valid, "a hard signal alone is enough")
+}
+
+func TestScore_RateLimitIsIdempotent(t *testing.T) {
+	d := newTestDetector(t, nil)
+	ctx := context.Background()
+	at := time.Now().Truncate(time.Minute).Add(30 * time.Second)
+	imp := impression(at.Add(-time.Minute))
+
+	for i := 0; i < d.cfg.MaxClicksPerMinute; i++ {
+		result := d.Score(ctx, click("c"+strconv.Itoa(i), at), imp)
+		assert.True(t, result.Valid)
+		// Redelivery of the same click must not count twice.
+		assert.True(t, d.Score(ctx, click("c"+strconv.Itoa(i), at), imp).Valid)
+	}
+
+	over := d.Score(ctx, click("extra", at), imp)
+	assert.Contains(t, over.Reasons, ReasonRateLimit)
+	assert.False(t, over.Valid)
+}
+
+func TestRecord_CountsOnlyRecordedClicks(t *testing.T) {
+	d := newTestDetector(t, nil)
+
+	bot := click("c1", time.Now())
+	bot.UserAgent = "curl/8.5.0"
+	result := d.Score(context.Background(), bot, nil)
+	d.Score(context.Background(), bot, nil)
+	assert.Zero(t, testutil.ToFloat64(d.metrics.clicksInvalid), "scoring alone is not counted")
+
+	d.Record(result)
+	assert.Equal(t, 1.0, testutil.ToFloat64(d.metrics.clicksInvalid))
+	assert.Equal(t, 1.0, testutil.ToFloat64(d.metrics.clicksFlagged.WithLabelValues(ReasonBotUserAgent)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(d.metrics.clicksFlagged.WithLabelValues(ReasonNoImpression)))
+}
+
+func TestLoadCIDRs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cidrs.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n\n10.0.0.0/8  # private\n2001:db8::/32\n"), 0644))
+
+	nets, err := LoadCIDRs(path)
+	require.NoError(t, err)
+	assert.Len(t, nets, 2)
+
+	require.NoError(t, os.WriteFile(path, []byte("not-a-cidr\n"), 0644))
+	_, err = LoadCIDRs(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package fraud
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics count the clicks a Detector flags, as recorded by Record.
+type Metrics struct {
+	clicksFlagged *prometheus.CounterVec
+	clicksInvalid prometheus.Counter
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		clicksFlagged: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "fraud_clicks_flagged_total",
+				Help: "Clicks matching each fraud rule",
+			},
+			[]string{"reason"},
+		),
+		clicksInvalid: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "fraud_clicks_invalid_total",
+				Help: "Clicks scored at or above the fraud threshold and excluded from billable counts",
+			},
+		),
+	}
+}
+
+// Register registers fraud scoring metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.clicksFlagged, m.clicksInvalid} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
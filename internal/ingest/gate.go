@@ -0,0 +1,163 @@
+// Package ingest sheds tracking events when the pipeline behind the HTTP
+// handlers is overloaded, so a surge degrades predictably instead of growing
+// the queue or the fallback file without bound.
+package ingest
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons an event is shed, as recorded in ingest_events_shed_total.
+const (
+	reasonQueueHighWater = "queue_high_water"
+	reasonQueueHardLimit = "queue_hard_limit"
+	reasonFallbackFull   = "fallback_full"
+)
+
+// Gate admits or sheds events by the last sampled queue depth and fallback
+// file size. Events of the configured priority kinds are kept until the
+// queue reaches its hard limit; the rest are shed from the high-water mark.
+// A full fallback file sheds everything, since it is where events go when
+// the queue cannot take them.
+type Gate struct {
+	queue    queue.Queue
+	fallback *clicks.Fallback
+	metrics  *Metrics
+	logger   logrus.FieldLogger
+
+	mu       sync.Mutex
+	cfg      config.IngestConfig
+	priority map[string]bool
+	reset    chan struct{}
+	done     chan struct{}
+
+	depth         atomic.Int64
+	fallbackBytes atomic.Int64
+}
+
+func NewGate(q queue.Queue, fallback *clicks.Fallback, cfg config.IngestConfig, metrics *Metrics, logger logrus.FieldLogger) *Gate {
+	g := &Gate{queue: q, fallback: fallback, metrics: metrics, logger: logger, reset: make(chan struct{}, 1), done: make(chan struct{})}
+	g.SetConfig(cfg)
+	return g
+}
+
+// SetConfig replaces the thresholds, e.g. on reload.
+func (g *Gate) SetConfig(cfg config.IngestConfig) {
+	priority := make(map[string]bool)
+	for _, kind := range cfg.Kinds() {
+		priority[kind] = true
+	}
+
+	g.mu.Lock()
+	g.cfg, g.priority = cfg, priority
+	g.mu.Unlock()
+
+	select {
+	case g.reset <- struct{}{}:
+	default:
+	}
+}
+
+func (g *Gate) config() (config.IngestConfig, map[string]bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cfg, g.priority
+}
+
+// Run samples the queue depth and fallback size until ctx is cancelled.
+// Wait blocks until it has stopped.
+func (g *Gate) Run(ctx context.Context) {
+	g.Sample(ctx)
+	go func() {
+		defer close(g.done)
+		cfg, _ := g.config()
+		timer := time.NewTimer(cfg.SampleInterval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-g.reset:
+				timer.Stop()
+			case <-timer.C:
+				g.Sample(ctx)
+			}
+			cfg, _ := g.config()
+			timer.Reset(cfg.SampleInterval)
+		}
+	}()
+}
+
+// Wait blocks until the sampling started by Run has stopped.
+func (g *Gate) Wait() {
+	<-g.done
+}
+
+// Sample refreshes the queue depth and fallback size. A failed read keeps
+// the previous value: an unreachable queue shows in the fallback size.
+func (g *Gate) Sample(ctx context.Context) {
+	cfg, _ := g.config()
+	ctx, cancel := context.WithTimeout(ctx, cfg.SampleInterval)
+	defer cancel()
+
+	if depth, err := g.queue.Depth(ctx); err != nil {
+		g.logger.WithError(err).Debug("Failed to sample queue depth")
+	} else {
+		g.depth.Store(depth.Ready)
+	}
+	if size, err := g.fallback.Size(); err != nil {
+		g.logger.WithError(err).Warn("Failed to sample fallback file size")
+	} else {
+		g.fallbackBytes.Store(size)
+	}
+}
+
+// check returns the status and reason an event of kind is shed with, or
+// zero if it is admitted.
+func (g *Gate) check(kind string) (status int, reason string) {
+	cfg, priority := g.config()
+	depth := g.depth.Load()
+	switch {
+	case g.fallbackBytes.Load() > int64(cfg.FallbackMaxBytes):
+		return http.StatusServiceUnavailable, reasonFallbackFull
+	case depth > int64(cfg.QueueHardLimit):
+		return http.StatusTooManyRequests, reasonQueueHardLimit
+	case depth > int64(cfg.QueueHighWater) && !priority[kind]:
+		return http.StatusTooManyRequests, reasonQueueHighWater
+	}
+	return 0, ""
+}
+
+// Admit returns middleware that sheds events of kind, responding with
+// Retry-After, while the pipeline is over its limits.
+func (g *Gate) Admit(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, reason := g.check(kind)
+		if status == 0 {
+			c.Next()
+			return
+		}
+
+		g.metrics.eventsShed.WithLabelValues(kind, reason).Inc()
+		cfg, _ := g.config()
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(cfg.RetryAfter.Seconds()))))
+		message := "Too many events queued, retry later"
+		if status == http.StatusServiceUnavailable {
+			message = "Event storage unavailable, retry later"
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,102 @@
+package ingest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() config.IngestConfig {
+	cfg := config.Default().Ingest
+	cfg.QueueHighWater = 2
+	cfg.QueueHardLimit = 4
+	cfg.FallbackMaxBytes = 10
+	cfg.RetryAfter = 1500 * time.Millisecond
+	return cfg
+}
+
+func fill(t *testing.T, q queue.Queue, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, q.Enqueue(context.Background(), []byte(`{}`)))
+	}
+}
+
+func serve(g *Gate, kind string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", g.Admit(kind), func(c *gin.Context) { c.Status(http.StatusAccepted) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	return w
+}
+
+func TestGate_ShedsByPriority(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory()
+	g := NewGate(q, clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl")), testConfig(), NewMetrics(), logs.Discard())
+
+	fill(t, q, 2)
+	g.Sample(ctx)
+	assert.Equal(t, http.StatusAccepted, serve(g, clicks.KindVideo).Code, "at the high-water mark")
+
+	fill(t, q, 1)
+	g.Sample(ctx)
+	w := serve(g, clicks.KindVideo)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(g.metrics.eventsShed.WithLabelValues(clicks.KindVideo, reasonQueueHighWater)))
+	assert.Equal(t, http.StatusAccepted, serve(g, clicks.KindClick).Code, "clicks are kept")
+
+	fill(t, q, 2)
+	g.Sample(ctx)
+	assert.Equal(t, http.StatusTooManyRequests, serve(g, clicks.KindClick).Code, "over the hard limit")
+
+	cfg := testConfig()
+	cfg.QueueHardLimit = 10
+	g.SetConfig(cfg)
+	assert.Equal(t, http.StatusAccepted, serve(g, clicks.KindClick).Code, "new limits apply at once")
+}
+
+func TestGate_ShedsWhenFallbackFull(t *testing.T) {
+	fallback := clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl"))
+	g := NewGate(queue.NewMemory(), fallback, testConfig(), NewMetrics(), logs.Discard())
+	require.NoError(t, os.WriteFile(fallback.Path, []byte("{}\n{}\n{}\n{}\n"), 0644))
+	g.Sample(context.Background())
+
+	w := serve(g, clicks.KindConversion)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Event storage unavailable, retry later"}`, w.Body.String())
+}
+
+func TestGate_WaitReturnsOnceRunStops(t *testing.T) {
+	cfg := testConfig()
+	cfg.SampleInterval = time.Millisecond
+	g := NewGate(queue.NewMemory(), clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl")), cfg, NewMetrics(), logs.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.Run(ctx)
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	done := make(chan struct{})
+	go func() { g.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sampling did not stop")
+	}
+}
@@ -0,0 +1,28 @@
+package ingest
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics count the events a Gate sheds.
+type Metrics struct {
+	eventsShed *prometheus.CounterVec
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		eventsShed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ingest_events_shed_total",
+				Help: "Tracking events rejected by load shedding, by kind and reason",
+			},
+			[]string{"kind", "reason"},
+		),
+	}
+}
+
+// Register registers load shedding metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	return reg.Register(m.eventsShed)
+}
@@ -0,0 +1,148 @@
+// Package leader elects one replica to run the singleton background jobs,
+// using a Redis key as a lease that the leader keeps renewing.
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// renewScript extends the lease only if this instance still holds it.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lease only if this instance still holds it.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Elector campaigns for the lease at key. The holder renews it every renew
+// interval; if it dies, the lease expires after ttl and another replica
+// takes over. A leader that cannot renew in time steps down before its lease
+// can have expired, so two replicas never both believe they lead.
+type Elector struct {
+	rdb     *redis.Client
+	key     string
+	id      string
+	ttl     time.Duration
+	renew   time.Duration
+	metrics *Metrics
+	logger  logrus.FieldLogger
+
+	leading atomic.Bool
+	done    chan struct{}
+}
+
+// NewElector returns an elector for the instance id. Nothing happens until
+// Start.
+func NewElector(rdb *redis.Client, key, id string, ttl, renew time.Duration, metrics *Metrics, logger logrus.FieldLogger) *Elector {
+	return &Elector{
+		rdb:     rdb,
+		key:     key,
+		id:      id,
+		ttl:     ttl,
+		renew:   renew,
+		metrics: metrics,
+		logger:  logger.WithFields(logrus.Fields{"leaderKey": key, "instance": id}),
+		done:    make(chan struct{}),
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Start campaigns until ctx is cancelled, then releases the lease if held.
+// Wait blocks until it has.
+func (e *Elector) Start(ctx context.Context) {
+	e.metrics.isLeader.Set(0)
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.renew)
+		defer ticker.Stop()
+
+		for {
+			e.campaign(ctx)
+			select {
+			case <-ctx.Done():
+				e.release()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until Start's campaign has ended.
+func (e *Elector) Wait() {
+	<-e.done
+}
+
+// campaign renews the lease if held, or tries to acquire it.
+func (e *Elector) campaign(ctx context.Context) {
+	// A leader must give up before its lease could have expired.
+	ctx, cancel := context.WithTimeout(ctx, e.ttl-e.renew)
+	defer cancel()
+
+	if e.IsLeader() {
+		held, err := renewScript.Run(ctx, e.rdb, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+		if err != nil {
+			e.logger.WithError(err).Warn("Failed to renew leadership lease")
+		}
+		if (err != nil || held == 0) && e.set(false) {
+			e.logger.Warn("Lost leadership")
+		}
+		return
+	}
+
+	acquired, err := e.rdb.SetNX(ctx, e.key, e.id, e.ttl).Result()
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			e.logger.WithError(err).Debug("Failed to campaign for leadership")
+		}
+		return
+	}
+	if acquired && e.set(true) {
+		e.logger.Info("Acquired leadership")
+	}
+}
+
+// release gives the lease up so another replica can take over at once
+// instead of waiting for it to expire.
+func (e *Elector) release() {
+	if !e.IsLeader() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.renew)
+	defer cancel()
+	if err := releaseScript.Run(ctx, e.rdb, []string{e.key}, e.id).Err(); err != nil {
+		e.logger.WithError(err).Warn("Failed to release leadership lease")
+	}
+	e.set(false)
+	e.logger.Info("Released leadership")
+}
+
+// set records whether this instance leads and reports whether that changed.
+func (e *Elector) set(leading bool) bool {
+	if e.leading.Swap(leading) == leading {
+		return false
+	}
+	if leading {
+		e.metrics.isLeader.Set(1)
+	} else {
+		e.metrics.isLeader.Set(0)
+	}
+	e.metrics.transitions.Inc()
+	return true
+}
@@ -0,0 +1,77 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const key = "test:leader"
+
+func newElector(t *testing.T, mr *miniredis.Miniredis, id string) *Elector {
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewElector(rdb, key, id, time.Second, 10*time.Millisecond, NewMetrics(), logs.Discard())
+}
+
+func TestElector_HandsOverOnRelease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newElector(t, mr, "a"), newElector(t, mr, "b")
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	a.Start(ctxA)
+	require.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer func() { cancelB(); b.Wait() }()
+	b.Start(ctxB)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, b.IsLeader(), "only one leader")
+	assert.True(t, a.IsLeader(), "leader keeps renewing")
+
+	cancelA()
+	a.Wait()
+	assert.False(t, a.IsLeader())
+	require.Eventually(t, b.IsLeader, time.Second, 5*time.Millisecond)
+	got, _ := mr.Get(key)
+	assert.Equal(t, "b", got)
+}
+
+func TestElector_TakesOverExpiredLease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set(key, "dead"))
+	mr.SetTTL(key, time.Second)
+
+	e := newElector(t, mr, "e")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); e.Wait() }()
+	e.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, e.IsLeader())
+
+	mr.FastForward(2 * time.Second)
+	require.Eventually(t, e.IsLeader, time.Second, 5*time.Millisecond)
+}
+
+func TestElector_StepsDownWhenLeaseLost(t *testing.T) {
+	mr := miniredis.RunT(t)
+	e := newElector(t, mr, "e")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); e.Wait() }()
+	e.Start(ctx)
+	require.Eventually(t, e.IsLeader, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, mr.Set(key, "usurper"))
+	require.Eventually(t, func() bool { return !e.IsLeader() }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	e.Wait()
+	got, _ := mr.Get(key)
+	assert.Equal(t, "usurper", got, "release leaves another holder's lease alone")
+}
@@ -0,0 +1,39 @@
+package leader
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics report the leadership of an Elector.
+type Metrics struct {
+	isLeader    prometheus.Gauge
+	transitions prometheus.Counter
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		isLeader: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "leader_is_leader",
+				Help: "1 while this instance holds the lease for the singleton background jobs",
+			},
+		),
+		transitions: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "leader_transitions_total",
+				Help: "Times this instance acquired or lost leadership",
+			},
+		),
+	}
+}
+
+// Register registers leader election metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.isLeader, m.transitions} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
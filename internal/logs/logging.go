@@ -1,25 +1,36 @@
 package logs
 
 import (
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
 )
 
-var Logger = logrus.New()
+// New returns the application logger. It appends JSON lines to path, or
+// writes to stdout if the file cannot be opened.
+func New(path string) *logrus.Logger {
+	logger := logrus.New()
 
-func InitLogger() {
 	// Create or append to log file
-	file, err := os.OpenFile("./internal/logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		// Fallback to stdout only if file cannot be opened
-		Logger.SetOutput(os.Stdout)
-		Logger.Warn("Failed to log to file, using default stderr")
+		logger.SetOutput(os.Stdout)
+		logger.Warn("Failed to log to file, using stdout")
 	} else {
-		Logger.SetOutput(file)
+		logger.SetOutput(file)
 	}
 
-	Logger.SetFormatter(&logrus.JSONFormatter{})
-	Logger.SetLevel(logrus.InfoLevel)
-	Logger.SetReportCaller(true)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetReportCaller(true)
+	return logger
+}
+
+// Discard returns a logger that drops everything, for tests.
+func Discard() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
 }
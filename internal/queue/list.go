@@ -0,0 +1,150 @@
+package queue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveScript moves the next ready message to the processing list and
+// records when it was reserved.
+var reserveScript = redis.NewScript(`
+local data = redis.call("RPOPLPUSH", KEYS[1], KEYS[2])
+if data then
+	redis.call("HSET", KEYS[3], data, ARGV[1])
+end
+return data`)
+
+// reclaimScript moves messages reserved longer than ARGV[2] ms ago back to
+// the end of the ready list that is reserved next. A processing entry without a reservation time
+// is stamped now, so it is reclaimed one timeout later.
+var reclaimScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local reclaimed = 0
+for _, data in ipairs(redis.call("LRANGE", KEYS[2], 0, -1)) do
+	local reserved = redis.call("HGET", KEYS[3], data)
+	if not reserved then
+		redis.call("HSET", KEYS[3], data, now)
+	elseif now - tonumber(reserved) >= tonumber(ARGV[2]) then
+		redis.call("LREM", KEYS[2], 1, data)
+		redis.call("HDEL", KEYS[3], data)
+		redis.call("RPUSH", KEYS[1], data)
+		reclaimed = reclaimed + 1
+	end
+end
+return reclaimed`)
+
+// RedisList is a queue on Redis lists: producers LPUSH onto <name>_queue and
+// consumers RPOPLPUSH into <name>_processing, so an in-flight message is
+// never only in a worker's memory. Reservation times are kept in the
+// <name>_reserved hash; messages left in the processing list by a worker
+// that died are requeued once they have been in flight for longer than the
+// claim timeout. Dead letters go to <name>_dead.
+type RedisList struct {
+	rdb          *redis.Client
+	ready        string
+	processing   string
+	reserved     string
+	dead         string
+	claimTimeout time.Duration
+	// nextReclaim is when Reserve next looks for abandoned messages, in
+	// Unix nanoseconds.
+	nextReclaim atomic.Int64
+}
+
+// NewRedisList returns the list queue called name; "click" is the
+// click_queue / click_processing / click_dead trio.
+func NewRedisList(rdb *redis.Client, name string, claimTimeout time.Duration) *RedisList {
+	return &RedisList{
+		rdb:          rdb,
+		ready:        name + "_queue",
+		processing:   name + "_processing",
+		reserved:     name + "_reserved",
+		dead:         name + "_dead",
+		claimTimeout: claimTimeout,
+	}
+}
+
+func (q *RedisList) Enqueue(ctx context.Context, body []byte) error {
+	return q.rdb.LPush(ctx, q.ready, body).Err()
+}
+
+// Reserve uses the payload itself as the message ID, since that is what
+// LREM needs to remove it from the processing list. Every quarter of the
+// claim timeout it first requeues abandoned messages, so one is redelivered
+// at most 1.25 claim timeouts after it was reserved.
+func (q *RedisList) Reserve(ctx context.Context) (Message, error) {
+	now := time.Now()
+	if next := q.nextReclaim.Load(); now.UnixNano() >= next &&
+		q.nextReclaim.CompareAndSwap(next, now.Add(q.claimTimeout/4).UnixNano()) {
+		if _, err := q.Reclaim(ctx); err != nil {
+			return Message{}, err
+		}
+	}
+
+	data, err := reserveScript.Run(ctx, q.rdb, []string{q.ready, q.processing, q.reserved}, now.UnixMilli()).Text()
+	if err == redis.Nil {
+		return Message{}, ErrEmpty
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{ID: data, Body: []byte(data)}, nil
+}
+
+// Reclaim requeues the messages that have been in flight for longer than the
+// claim timeout and returns how many it moved. They are delivered next.
+func (q *RedisList) Reclaim(ctx context.Context) (int64, error) {
+	return reclaimScript.Run(ctx, q.rdb, []string{q.ready, q.processing, q.reserved},
+		time.Now().UnixMilli(), q.claimTimeout.Milliseconds()).Int64()
+}
+
+// Peek reads the tail of the ready list, which is the next to be reserved.
+func (q *RedisList) Peek(ctx context.Context) (Message, error) {
+	data, err := q.rdb.LIndex(ctx, q.ready, -1).Result()
+	if err == redis.Nil {
+		return Message{}, ErrEmpty
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{ID: data, Body: []byte(data)}, nil
+}
+
+func (q *RedisList) Ack(ctx context.Context, msg Message) error {
+	pipe := q.rdb.TxPipeline()
+	pipe.LRem(ctx, q.processing, 1, msg.ID)
+	pipe.HDel(ctx, q.reserved, msg.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (q *RedisList) Nack(ctx context.Context, msg Message, body []byte) error {
+	return q.move(ctx, msg, q.ready, body)
+}
+
+func (q *RedisList) DeadLetter(ctx context.Context, msg Message, body []byte) error {
+	return q.move(ctx, msg, q.dead, body)
+}
+
+func (q *RedisList) move(ctx context.Context, msg Message, to string, body []byte) error {
+	pipe := q.rdb.TxPipeline()
+	pipe.LPush(ctx, to, body)
+	pipe.LRem(ctx, q.processing, 1, msg.ID)
+	pipe.HDel(ctx, q.reserved, msg.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (q *RedisList) Depth(ctx context.Context) (Depth, error) {
+	pipe := q.rdb.Pipeline()
+	ready := pipe.LLen(ctx, q.ready)
+	inFlight := pipe.LLen(ctx, q.processing)
+	dead := pipe.LLen(ctx, q.dead)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Depth{}, err
+	}
+	return Depth{Ready: ready.Val(), InFlight: inFlight.Val(), Dead: dead.Val()}, nil
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// Memory is an in-process queue for tests and single-instance development.
+// Messages do not survive a restart.
+type Memory struct {
+	mu       sync.Mutex
+	seq      int
+	ready    [][]byte
+	inFlight map[string][]byte
+	dead     [][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{inFlight: make(map[string][]byte)}
+}
+
+func (q *Memory) Enqueue(_ context.Context, body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ready = append(q.ready, append([]byte(nil), body...))
+	return nil
+}
+
+func (q *Memory) Reserve(_ context.Context) (Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.ready) == 0 {
+		return Message{}, ErrEmpty
+	}
+	body := q.ready[0]
+	q.ready = q.ready[1:]
+	q.seq++
+	id := strconv.Itoa(q.seq)
+	q.inFlight[id] = body
+	return Message{ID: id, Body: body}, nil
+}
+
+func (q *Memory) Peek(_ context.Context) (Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.ready) == 0 {
+		return Message{}, ErrEmpty
+	}
+	return Message{Body: q.ready[0]}, nil
+}
+
+func (q *Memory) Ack(_ context.Context, msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, msg.ID)
+	return nil
+}
+
+func (q *Memory) Nack(_ context.Context, msg Message, body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, msg.ID)
+	q.ready = append(q.ready, append([]byte(nil), body...))
+	return nil
+}
+
+func (q *Memory) DeadLetter(_ context.Context, msg Message, body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, msg.ID)
+	q.dead = append(q.dead, append([]byte(nil), body...))
+	return nil
+}
+
+func (q *Memory) Depth(_ context.Context) (Depth, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Depth{Ready: int64(len(q.ready)), InFlight: int64(len(q.inFlight)), Dead: int64(len(q.dead))}, nil
+}
+
+// DeadLetters returns copies of the dead-lettered payloads, oldest first.
+func (q *Memory) DeadLetters() [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([][]byte, len(q.dead))
+	for i, body := range q.dead {
+		out[i] = append([]byte(nil), body...)
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+// Package queue is the transport between the HTTP handlers, which enqueue
+// tracking events, and the workers, which reserve and process them.
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEmpty is returned by Reserve when no message is ready.
+var ErrEmpty = errors.New("queue: empty")
+
+// Message is a reserved payload. ID identifies this delivery to Ack, Nack
+// and DeadLetter and is only meaningful to the queue that returned it.
+type Message struct {
+	ID   string
+	Body []byte
+}
+
+// Depth counts messages by state.
+type Depth struct {
+	Ready    int64 `json:"ready"`
+	InFlight int64 `json:"inFlight"`
+	Dead     int64 `json:"dead"`
+}
+
+// Queue is an at-least-once work queue. A reserved message stays in flight
+// until it is acked, nacked or dead-lettered.
+type Queue interface {
+	Enqueue(ctx context.Context, body []byte) error
+	// Reserve takes the next ready message, or returns ErrEmpty.
+	Reserve(ctx context.Context) (Message, error)
+	// Peek returns the oldest ready message without reserving it, or
+	// ErrEmpty. Its ID is not valid for Ack, Nack or DeadLetter.
+	Peek(ctx context.Context) (Message, error)
+	// Ack removes a processed message.
+	Ack(ctx context.Context, msg Message) error
+	// Nack puts a message back for another attempt. body replaces its
+	// payload, e.g. with an incremented retry count.
+	Nack(ctx context.Context, msg Message, body []byte) error
+	// DeadLetter moves a message that will not be retried aside for inspection.
+	DeadLetter(ctx context.Context, msg Message, body []byte) error
+	Depth(ctx context.Context) (Depth, error)
+}
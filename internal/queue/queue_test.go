@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// implementations builds a fresh instance of every Queue so that each runs
+// the same behavioural tests.
+func implementations(t *testing.T) map[string]Queue {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	stream, err := NewRedisStream(context.Background(), rdb, "events", "workers", "test", time.Minute)
+	require.NoError(t, err)
+	stream.block = 10 * time.Millisecond
+
+	return map[string]Queue{
+		"memory": NewMemory(),
+		"list":   NewRedisList(rdb, "click", time.Minute),
+		"stream": stream,
+	}
+}
+
+func forEach(t *testing.T, test func(t *testing.T, q Queue)) {
+	for name, q := range implementations(t) {
+		q := q
+		t.Run(name, func(t *testing.T) { test(t, q) })
+	}
+}
+
+func TestQueue_FIFOAndAck(t *testing.T) {
+	forEach(t, func(t *testing.T, q Queue) {
+		ctx := context.Background()
+		require.NoError(t, q.Enqueue(ctx, []byte("a")))
+		require.NoError(t, q.Enqueue(ctx, []byte("b")))
+
+		msg, err := q.Reserve(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(msg.Body))
+
+		depth, err := q.Depth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Depth{Ready: 1, InFlight: 1}, depth)
+
+		require.NoError(t, q.Ack(ctx, msg))
+		msg, err = q.Reserve(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(msg.Body))
+		require.NoError(t, q.Ack(ctx, msg))
+
+		_, err = q.Reserve(ctx)
+		assert.ErrorIs(t, err, ErrEmpty)
+		depth, err = q.Depth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Depth{}, depth)
+	})
+}
+
+func TestQueue_NackRedelivers(t *testing.T) {
+	forEach(t, func(t *testing.T, q Queue) {
+		ctx := context.Background()
+		require.NoError(t, q.Enqueue(ctx, []byte(`{"retry":0}`)))
+
+		msg, err := q.Reserve(ctx)
+		require.NoError(t, err)
+		require.NoError(t, q.Nack(ctx, msg, []byte(`{"retry":1}`)))
+
+		msg, err = q.Reserve(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, `{"retry":1}`, string(msg.Body))
+
+		depth, err := q.Depth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Depth{InFlight: 1}, depth)
+	})
+}
+
+func TestQueue_DeadLetter(t *testing.T) {
+	forEach(t, func(t *testing.T, q Queue) {
+		ctx := context.Background()
+		require.NoError(t, q.Enqueue(ctx, []byte("poison")))
+
+		msg, err := q.Reserve(ctx)
+		require.NoError(t, err)
+		require.NoError(t, q.DeadLetter(ctx, msg, []byte("poison")))
+
+		_, err = q.Reserve(ctx)
+		assert.ErrorIs(t, err, ErrEmpty)
+		depth, err := q.Depth(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Depth{Dead: 1}, depth)
+	})
+}
+
+func TestQueue_Peek(t *testing.T) {
+	forEach(t, func(t *testing.T, q Queue) {
+		ctx := context.Background()
+		_, err := q.Peek(ctx)
+		assert.ErrorIs(t, err, ErrEmpty)
+
+		require.NoError(t, q.Enqueue(ctx, []byte("a")))
+		require.NoError(t, q.Enqueue(ctx, []byte("b")))
+
+		msg, err := q.Peek(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(msg.Body))
+
+		reserved, err := q.Reserve(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(reserved.Body), "peek does not reserve")
+
+		msg, err = q.Peek(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(msg.Body), "in-flight messages are not ready")
+
+		require.NoError(t, q.Ack(ctx, reserved))
+		_, err = q.Reserve(ctx)
+		require.NoError(t, err)
+		_, err = q.Peek(ctx)
+		assert.ErrorIs(t, err, ErrEmpty)
+	})
+}
+
+func TestRedisList_ReclaimsAbandoned(t *testing.T) {
+	s := miniredis.RunT(t)
+	q := NewRedisList(redis.NewClient(&redis.Options{Addr: s.Addr()}), "click", 50*time.Millisecond)
+	ctx := context.Background()
+	require.NoError(t, q.Enqueue(ctx, []byte("a")))
+	require.NoError(t, q.Enqueue(ctx, []byte("b")))
+
+	// A worker reserves a and dies without acking it.
+	_, err := q.Reserve(ctx)
+	require.NoError(t, err)
+	n, err := q.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "not abandoned before the claim timeout")
+
+	time.Sleep(60 * time.Millisecond)
+	msg, err := q.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(msg.Body), "the abandoned message is delivered first")
+	require.NoError(t, q.Ack(ctx, msg))
+
+	depth, err := q.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Depth{Ready: 1}, depth)
+	assert.False(t, s.Exists("click_reserved"), "acked messages leave no reservation behind")
+}
+
+func TestRedisList_ReclaimStampsUnknownEntries(t *testing.T) {
+	s := miniredis.RunT(t)
+	q := NewRedisList(redis.NewClient(&redis.Options{Addr: s.Addr()}), "click", 50*time.Millisecond)
+	ctx := context.Background()
+	// Left behind by a version that did not record reservation times.
+	_, err := s.Lpush("click_processing", "old")
+	require.NoError(t, err)
+
+	n, err := q.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	time.Sleep(60 * time.Millisecond)
+	n, err = q.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	msg, err := q.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(msg.Body))
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bodyField is the stream entry field holding the payload.
+const bodyField = "body"
+
+// RedisStream is a queue on a Redis stream read through a consumer group.
+// Entries reserved by a consumer that died are claimed by another one once
+// they have been pending for longer than the claim timeout. Dead letters are
+// appended to <stream>:dead.
+type RedisStream struct {
+	rdb          *redis.Client
+	stream       string
+	dead         string
+	group        string
+	consumer     string
+	claimTimeout time.Duration
+	block        time.Duration
+}
+
+// NewRedisStream returns the stream queue, creating the consumer group if
+// needed. consumer must be unique per process, e.g. the hostname.
+func NewRedisStream(ctx context.Context, rdb *redis.Client, stream, group, consumer string, claimTimeout time.Duration) (*RedisStream, error) {
+	err := rdb.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+	return &RedisStream{
+		rdb:          rdb,
+		stream:       stream,
+		dead:         stream + ":dead",
+		group:        group,
+		consumer:     consumer,
+		claimTimeout: claimTimeout,
+		block:        time.Second,
+	}, nil
+}
+
+func (q *RedisStream) Enqueue(ctx context.Context, body []byte) error {
+	return q.rdb.XAdd(ctx, &redis.XAddArgs{Stream: q.stream, Values: map[string]interface{}{bodyField: body}}).Err()
+}
+
+// Reserve first reclaims an entry abandoned by another consumer, then waits
+// briefly for a new one.
+func (q *RedisStream) Reserve(ctx context.Context) (Message, error) {
+	claimed, _, err := q.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Consumer: q.consumer,
+		MinIdle:  q.claimTimeout,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil {
+		return Message{}, err
+	}
+	if len(claimed) > 0 {
+		return toMessage(claimed[0]), nil
+	}
+
+	streams, err := q.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{q.stream, ">"},
+		Count:    1,
+		Block:    q.block,
+	}).Result()
+	if err == redis.Nil {
+		return Message{}, ErrEmpty
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return Message{}, ErrEmpty
+	}
+	return toMessage(streams[0].Messages[0]), nil
+}
+
+// Peek returns the first entry after the group's last delivered ID, which
+// the next read would deliver.
+func (q *RedisStream) Peek(ctx context.Context) (Message, error) {
+	groups, err := q.rdb.XInfoGroups(ctx, q.stream).Result()
+	if err != nil {
+		return Message{}, err
+	}
+	last := "0-0"
+	for _, g := range groups {
+		if g.Name == q.group {
+			last = g.LastDeliveredID
+		}
+	}
+	entries, err := q.rdb.XRangeN(ctx, q.stream, "("+last, "+", 1).Result()
+	if err != nil {
+		return Message{}, err
+	}
+	if len(entries) == 0 {
+		return Message{}, ErrEmpty
+	}
+	return toMessage(entries[0]), nil
+}
+
+func toMessage(m redis.XMessage) Message {
+	body, _ := m.Values[bodyField].(string)
+	return Message{ID: m.ID, Body: []byte(body)}
+}
+
+func (q *RedisStream) Ack(ctx context.Context, msg Message) error {
+	pipe := q.rdb.TxPipeline()
+	pipe.XAck(ctx, q.stream, q.group, msg.ID)
+	pipe.XDel(ctx, q.stream, msg.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (q *RedisStream) Nack(ctx context.Context, msg Message, body []byte) error {
+	return q.move(ctx, msg, q.stream, body)
+}
+
+func (q *RedisStream) DeadLetter(ctx context.Context, msg Message, body []byte) error {
+	return q.move(ctx, msg, q.dead, body)
+}
+
+func (q *RedisStream) move(ctx context.Context, msg Message, to string, body []byte) error {
+	pipe := q.rdb.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{Stream: to, Values: map[string]interface{}{bodyField: body}})
+	pipe.XAck(ctx, q.stream, q.group, msg.ID)
+	pipe.XDel(ctx, q.stream, msg.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Depth counts acked entries as gone, since Ack deletes them; everything
+// else in the stream is either pending in the group or not yet delivered.
+func (q *RedisStream) Depth(ctx context.Context) (Depth, error) {
+	pipe := q.rdb.Pipeline()
+	length := pipe.XLen(ctx, q.stream)
+	pending := pipe.XPending(ctx, q.stream, q.group)
+	dead := pipe.XLen(ctx, q.dead)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return Depth{}, err
+	}
+	var inFlight int64
+	if p, err := pending.Result(); err == nil {
+		inFlight = p.Count
+	}
+	return Depth{Ready: length.Val() - inFlight, InFlight: inFlight, Dead: dead.Val()}, nil
+}
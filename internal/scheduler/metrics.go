@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes of a scheduled run, as recorded in scheduler_job_runs_total.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeSkipped   = "skipped"
+)
+
+// Metrics report the runs of a Scheduler's jobs.
+type Metrics struct {
+	jobRuns        *prometheus.CounterVec
+	jobDuration    *prometheus.HistogramVec
+	jobLastSuccess *prometheus.GaugeVec
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		jobRuns: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "scheduler_job_runs_total",
+				Help: "Background job runs by job and outcome; skipped runs found the previous one still in progress",
+			},
+			[]string{"job", "outcome"},
+		),
+		jobDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "scheduler_job_duration_seconds",
+				Help:    "Duration of background job runs",
+				Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+			},
+			[]string{"job"},
+		),
+		jobLastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "scheduler_job_last_success_timestamp_seconds",
+				Help: "Unix time of each background job's last successful run",
+			},
+			[]string{"job"},
+		),
+	}
+}
+
+// Register registers background job metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.jobRuns, m.jobDuration, m.jobLastSuccess} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
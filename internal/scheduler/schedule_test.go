@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+	for spec, want := range map[string]time.Time{
+		"@every 1m30s":     from.Add(90 * time.Second),
+		"@hourly":          time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC),
+		"@daily":           time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC),
+		"@weekly":          time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC),
+		"*/15 * * * *":     time.Date(2025, time.January, 15, 10, 15, 0, 0, time.UTC),
+		"30 2 * * *":       time.Date(2025, time.January, 16, 2, 30, 0, 0, time.UTC),
+		"0 9-17/4 * * 1-5": time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC),
+		"0 0 1 3,6 *":      time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 13 * 5":       time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC), // Friday or the 13th
+		"0 0 * * 7":        time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":       time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+	} {
+		s, err := Parse(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, want, s.Next(from), spec)
+		assert.Equal(t, spec, s.String())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@every",
+		"@every -1m",
+		"@every soon",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCron_NoMatch(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero(), "February never has 31 days")
+}
@@ -0,0 +1,300 @@
+// Package scheduler runs the background jobs: each job registers with an
+// interval or cron schedule and the scheduler adds jitter, bounds each run
+// with a timeout, never runs a job twice at once and keeps a short history
+// of its runs.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Triggers of a run.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrRunning    = errors.New("job is already running")
+	ErrNotLeader  = errors.New("job only runs on the leader")
+	ErrStopped    = errors.New("scheduler is stopped")
+)
+
+// Job is a unit of background work.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays each scheduled run by a random duration up to this
+	// long, so replicas do not all run at the same instant.
+	Jitter time.Duration
+	// Timeout bounds each run; zero means none.
+	Timeout time.Duration
+	// Singleton jobs only run on the elected leader.
+	Singleton bool
+	Run       func(ctx context.Context) error
+}
+
+// Run records one run of a job.
+type Run struct {
+	Trigger  string    `json:"trigger"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Status describes a job and its recent runs, newest first.
+type Status struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	Singleton bool      `json:"singleton"`
+	Running   bool      `json:"running"`
+	Next      time.Time `json:"next,omitempty"`
+	Runs      []Run     `json:"runs"`
+}
+
+type entry struct {
+	job     Job
+	reset   chan struct{}
+	running bool
+	next    time.Time
+	runs    []Run
+}
+
+// Scheduler runs registered jobs on their schedules from Start until Stop.
+type Scheduler struct {
+	leader  interface{ IsLeader() bool }
+	history int
+	metrics *Metrics
+	logger  logrus.FieldLogger
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	order   []string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// New returns a scheduler keeping the last history runs of each job.
+// Singleton jobs only run while leader reports leadership; a nil leader
+// means this is the only replica.
+func New(leader interface{ IsLeader() bool }, history int, metrics *Metrics, logger logrus.FieldLogger) *Scheduler {
+	return &Scheduler{
+		leader:  leader,
+		history: history,
+		metrics: metrics,
+		logger:  logger,
+		jobs:    make(map[string]*entry),
+	}
+}
+
+// Register adds a job. Jobs registered after Start are scheduled at once.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return errors.New("job needs a name, schedule and run function")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %q already registered", job.Name)
+	}
+	e := &entry{job: job, reset: make(chan struct{}, 1)}
+	s.jobs[job.Name] = e
+	s.order = append(s.order, job.Name)
+	if s.ctx != nil && !s.stopped {
+		s.wg.Add(1)
+		go s.loop(s.ctx, e)
+	}
+	return nil
+}
+
+// Reschedule replaces a job's schedule, e.g. on reload, restarting its
+// timer.
+func (s *Scheduler) Reschedule(name string, schedule Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	e.job.Schedule = schedule
+	select {
+	case e.reset <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start schedules the registered jobs until Stop is called or ctx is
+// cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, name := range s.order {
+		s.wg.Add(1)
+		go s.loop(s.ctx, s.jobs[name])
+	}
+}
+
+// Stop stops scheduling, cancels the runs in progress and waits for them to
+// return, or for ctx to be done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger starts a run of the named job now, outside its schedule.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	if s.ctx == nil {
+		return ErrStopped
+	}
+	return s.start(s.ctx, e, TriggerManual)
+}
+
+// Jobs returns the status of every job in registration order.
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.jobs[name]
+		out = append(out, Status{
+			Name:      name,
+			Schedule:  e.job.Schedule.String(),
+			Singleton: e.job.Singleton,
+			Running:   e.running,
+			Next:      e.next,
+			Runs:      append([]Run{}, e.runs...),
+		})
+	}
+	return out
+}
+
+// loop starts the job at each scheduled time until ctx is cancelled.
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		next := e.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			s.mu.Unlock()
+			s.logger.WithField("job", e.job.Name).Warn("Job schedule has no further runs")
+			return
+		}
+		if e.job.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(e.job.Jitter))))
+		}
+		e.next = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-e.reset:
+			timer.Stop()
+		case <-timer.C:
+			s.mu.Lock()
+			err := s.start(ctx, e, TriggerSchedule)
+			s.mu.Unlock()
+			if errors.Is(err, ErrRunning) {
+				s.metrics.jobRuns.WithLabelValues(e.job.Name, outcomeSkipped).Inc()
+				s.logger.WithField("job", e.job.Name).Warn("Skipped job run, previous run still in progress")
+			}
+		}
+	}
+}
+
+// start runs the job in the background unless it is already running or
+// this replica may not run it. s.mu must be held.
+func (s *Scheduler) start(ctx context.Context, e *entry, trigger string) error {
+	if s.stopped {
+		return ErrStopped
+	}
+	if e.running {
+		return ErrRunning
+	}
+	if e.job.Singleton && s.leader != nil && !s.leader.IsLeader() {
+		return ErrNotLeader
+	}
+	e.running = true
+	s.wg.Add(1)
+	go s.run(ctx, e, e.job, trigger)
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry, job Job, trigger string) {
+	defer s.wg.Done()
+	logger := s.logger.WithFields(logrus.Fields{"job": job.Name, "trigger": trigger})
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	run := Run{Trigger: trigger, Started: time.Now()}
+	err := safeRun(ctx, job.Run)
+	run.Finished = time.Now()
+	s.metrics.jobDuration.WithLabelValues(job.Name).Observe(run.Finished.Sub(run.Started).Seconds())
+	if err != nil {
+		run.Error = err.Error()
+		s.metrics.jobRuns.WithLabelValues(job.Name, outcomeFailed).Inc()
+		logger.WithError(err).Error("Job failed")
+	} else {
+		s.metrics.jobRuns.WithLabelValues(job.Name, outcomeSucceeded).Inc()
+		s.metrics.jobLastSuccess.WithLabelValues(job.Name).Set(float64(run.Finished.Unix()))
+		logger.WithField("duration", run.Finished.Sub(run.Started).String()).Debug("Job finished")
+	}
+
+	s.mu.Lock()
+	e.running = false
+	e.runs = append([]Run{run}, e.runs...)
+	if len(e.runs) > s.history {
+		e.runs = e.runs[:s.history]
+	}
+	s.mu.Unlock()
+}
+
+// safeRun turns a panicking job into a failed run.
+func safeRun(ctx context.Context, run func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLeader struct{ leading atomic.Bool }
+
+func (l *fakeLeader) IsLeader() bool { return l.leading.Load() }
+
+func start(t *testing.T, s *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, s.Stop(context.Background()))
+	})
+}
+
+func TestScheduler_RunsOnSchedule(t *testing.T) {
+	metrics := NewMetrics()
+	s := New(nil, 3, metrics, logs.Discard())
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{
+		Name:     "tick",
+		Schedule: Every(10 * time.Millisecond),
+		Run: func(context.Context) error {
+			if runs.Add(1) == 2 {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}))
+	start(t, s)
+
+	require.Eventually(t, func() bool { return runs.Load() >= 5 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return !s.Jobs()[0].Running }, time.Second, time.Millisecond)
+
+	status := s.Jobs()[0]
+	assert.Equal(t, "tick", status.Name)
+	assert.Equal(t, "@every 10ms", status.Schedule)
+	assert.Len(t, status.Runs, 3, "history is capped")
+	assert.False(t, status.Next.IsZero())
+	for _, run := range status.Runs {
+		assert.Equal(t, TriggerSchedule, run.Trigger)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.jobRuns.WithLabelValues("tick", outcomeFailed)))
+}
+
+func TestScheduler_PreventsOverlap(t *testing.T) {
+	metrics := NewMetrics()
+	s := New(nil, 10, metrics, logs.Discard())
+	release := make(chan struct{})
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{
+		Name:     "slow",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}))
+	start(t, s)
+
+	require.Eventually(t, func() bool { return s.Jobs()[0].Running }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, s.Trigger("slow"), ErrRunning)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.jobRuns.WithLabelValues("slow", outcomeSkipped)) > 0
+	}, time.Second, time.Millisecond, "scheduled runs are skipped while one is in progress")
+	assert.Equal(t, int32(1), runs.Load())
+	close(release)
+}
+
+func TestScheduler_TriggerAndTimeout(t *testing.T) {
+	s := New(nil, 10, NewMetrics(), logs.Discard())
+	require.NoError(t, s.Register(Job{
+		Name:     "stuck",
+		Schedule: Every(time.Hour),
+		Timeout:  10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	require.NoError(t, s.Register(Job{
+		Name:     "panics",
+		Schedule: Every(time.Hour),
+		Run:      func(context.Context) error { panic("oops") },
+	}))
+	assert.Error(t, s.Register(Job{Name: "stuck", Schedule: Every(time.Hour), Run: func(context.Context) error { return nil }}))
+	assert.ErrorIs(t, s.Trigger("stuck"), ErrStopped, "not started")
+	start(t, s)
+
+	assert.ErrorIs(t, s.Trigger("missing"), ErrUnknownJob)
+	require.NoError(t, s.Trigger("stuck"))
+	require.NoError(t, s.Trigger("panics"))
+	require.Eventually(t, func() bool {
+		jobs := s.Jobs()
+		return len(jobs[0].Runs) == 1 && len(jobs[1].Runs) == 1
+	}, time.Second, time.Millisecond)
+
+	jobs := s.Jobs()
+	assert.Equal(t, TriggerManual, jobs[0].Runs[0].Trigger)
+	assert.Equal(t, context.DeadlineExceeded.Error(), jobs[0].Runs[0].Error)
+	assert.Equal(t, "panic: oops", jobs[1].Runs[0].Error)
+}
+
+func TestScheduler_SingletonOnlyOnLeader(t *testing.T) {
+	leader := &fakeLeader{}
+	s := New(leader, 10, NewMetrics(), logs.Discard())
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{
+		Name:      "singleton",
+		Schedule:  Every(5 * time.Millisecond),
+		Singleton: true,
+		Run:       func(context.Context) error { runs.Add(1); return nil },
+	}))
+	start(t, s)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Zero(t, runs.Load(), "followers do not run singleton jobs")
+	assert.ErrorIs(t, s.Trigger("singleton"), ErrNotLeader)
+
+	leader.leading.Store(true)
+	require.Eventually(t, func() bool { return runs.Load() > 0 }, time.Second, time.Millisecond)
+}
+
+func TestScheduler_Reschedule(t *testing.T) {
+	s := New(nil, 10, NewMetrics(), logs.Discard())
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{
+		Name:     "later",
+		Schedule: Every(time.Hour),
+		Run:      func(context.Context) error { runs.Add(1); return nil },
+	}))
+	start(t, s)
+
+	require.NoError(t, s.Reschedule("later", Every(5*time.Millisecond)))
+	require.Eventually(t, func() bool { return runs.Load() > 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, "@every 5ms", s.Jobs()[0].Schedule)
+	assert.ErrorIs(t, s.Reschedule("missing", Every(time.Second)), ErrUnknownJob)
+}
+
+func TestScheduler_StopCancelsRuns(t *testing.T) {
+	s := New(nil, 10, NewMetrics(), logs.Discard())
+	started := make(chan struct{})
+	require.NoError(t, s.Register(Job{
+		Name:     "long",
+		Schedule: Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	s.Start(context.Background())
+	require.NoError(t, s.Trigger("long"))
+	<-started
+
+	require.NoError(t, s.Stop(context.Background()))
+	assert.ErrorIs(t, s.Trigger("long"), ErrStopped)
+	assert.Equal(t, context.Canceled.Error(), s.Jobs()[0].Runs[0].Error)
+}
@@ -0,0 +1,41 @@
+package signing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics count the tokens a Signer accepts and rejects.
+type Metrics struct {
+	tokenAccepted *prometheus.CounterVec
+	tokenRejected *prometheus.CounterVec
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		tokenAccepted: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tracking_tokens_accepted_total",
+				Help: "Tracking events accepted with a valid signed token",
+			},
+			[]string{"event"},
+		),
+		tokenRejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tracking_tokens_rejected_total",
+				Help: "Tracking events rejected by token verification, by reason (forged, replayed, expired, ...)",
+			},
+			[]string{"event", "reason"},
+		),
+	}
+}
+
+// Register registers token verification metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.tokenAccepted, m.tokenRejected} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMalformed    = errors.New("signing: malformed token")
+	ErrUnknownKey   = errors.New("signing: unknown key id")
+	ErrBadSignature = errors.New("signing: signature mismatch")
+	ErrExpired      = errors.New("signing: token expired")
+	ErrAdMismatch   = errors.New("signing: token issued for a different ad")
+	ErrReplayed     = errors.New("signing: token already used for this event")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Token is the signed claim issued with every served ad.
+type Token struct {
+	AdID         string `json:"a"`
+	ImpressionID string `json:"i"`
+	ViewerID     string `json:"v,omitempty"`
+	ExperimentID string `json:"x,omitempty"`
+	Arm          string `json:"r,omitempty"`
+	IssuedAt     int64  `json:"t"`
+	Nonce        string `json:"n"`
+}
+
+// Key is one HMAC secret identified by a short key ID.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Signer issues and verifies tracking tokens. Tokens are signed with the
+// first (active) key and verified against every configured key, so a new key
+// can be rolled out ahead of the old one being retired.
+type Signer struct {
+	keys    map[string][]byte
+	active  string
+	ttl     time.Duration
+	replay  *redis.Client
+	metrics *Metrics
+	logger  logrus.FieldLogger
+}
+
+// NewSigner builds a Signer. rdb is used to reject replayed tokens and may be
+// nil, in which case only signature and expiry are checked.
+func NewSigner(keys []Key, ttl time.Duration, rdb *redis.Client, metrics *Metrics, logger logrus.FieldLogger) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("signing: at least one key is required")
+	}
+	s := &Signer{keys: make(map[string][]byte, len(keys)), active: keys[0].ID, ttl: ttl, replay: rdb, metrics: metrics, logger: logger}
+	for _, k := range keys {
+		if k.ID == "" || strings.Contains(k.ID, ".") || len(k.Secret) == 0 {
+			return nil, fmt.Errorf("signing: invalid key %q", k.ID)
+		}
+		s.keys[k.ID] = k.Secret
+	}
+	return s, nil
+}
+
+// ParseKeys parses "kid:secret,kid:secret". The first key becomes the active signing key.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(part, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("signing: invalid key entry %q, expected kid:secret", part)
+		}
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("signing: no keys configured")
+	}
+	return keys, nil
+}
+
+// RandomKey generates an ephemeral key for when none is configured.
+func RandomKey() Key {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return Key{ID: "ephemeral", Secret: []byte(hex.EncodeToString(secret))}
+}
+
+// Issue creates and signs a fresh token for adID served to viewerID, with a
+// new impression ID and nonce.
+func (s *Signer) Issue(adID, viewerID string) (Token, string, error) {
+	return s.IssueClaims(Token{AdID: adID, ViewerID: viewerID})
+}
+
+// IssueClaims signs claims as a fresh token, filling in the impression ID,
+// issue time and nonce.
+func (s *Signer) IssueClaims(claims Token) (Token, string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return Token{}, "", err
+	}
+	claims.ImpressionID = uuid.New().String()
+	claims.IssuedAt = time.Now().Unix()
+	claims.Nonce = b64.EncodeToString(nonce)
+	raw, err := s.Sign(claims)
+	return claims, raw, err
+}
+
+// Sign encodes tok as "kid.payload.signature" using the active key.
+func (s *Signer) Sign(tok Token) (string, error) {
+	payload, err := json.Marshal(tok)
+	if err != nil {
+		return "", err
+	}
+	body := s.active + "." + b64.EncodeToString(payload)
+	return body + "." + b64.EncodeToString(mac(s.keys[s.active], body)), nil
+}
+
+// Verify checks the signature and expiry of raw and returns its claims.
+func (s *Signer) Verify(raw string) (Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return Token{}, ErrMalformed
+	}
+	secret, ok := s.keys[parts[0]]
+	if !ok {
+		return Token{}, ErrUnknownKey
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return Token{}, ErrMalformed
+	}
+	if !hmac.Equal(sig, mac(secret, parts[0]+"."+parts[1])) {
+		return Token{}, ErrBadSignature
+	}
+
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return Token{}, ErrMalformed
+	}
+	var tok Token
+	if err := json.Unmarshal(payload, &tok); err != nil || tok.AdID == "" || tok.Nonce == "" {
+		return Token{}, ErrMalformed
+	}
+	if time.Since(time.Unix(tok.IssuedAt, 0)) > s.ttl {
+		return Token{}, ErrExpired
+	}
+	return tok, nil
+}
+
+// Validate verifies raw for a tracking event. adID, when non-empty, must
+// match the token. When replay protection is enabled and once is true the
+// token is accepted only the first time it is used for event. Every outcome
+// is counted in the tracking token metrics.
+func (s *Signer) Validate(ctx context.Context, raw, adID, event string, once bool) (Token, error) {
+	tok, err := s.Verify(raw)
+	if err == nil && adID != "" && adID != tok.AdID {
+		err = ErrAdMismatch
+	}
+	if err == nil && once && s.replay != nil {
+		err = s.checkReplay(ctx, tok, event)
+	}
+	if err != nil {
+		s.metrics.tokenRejected.WithLabelValues(event, reason(err)).Inc()
+		return Token{}, err
+	}
+	s.metrics.tokenAccepted.WithLabelValues(event).Inc()
+	return tok, nil
+}
+
+// checkReplay records the (nonce, event) pair until the token expires. If
+// Redis is unavailable the event is let through rather than dropped.
+func (s *Signer) checkReplay(ctx context.Context, tok Token, event string) error {
+	ttl := time.Until(time.Unix(tok.IssuedAt, 0).Add(s.ttl))
+	if ttl <= 0 {
+		return ErrExpired
+	}
+	first, err := s.replay.SetNX(ctx, "token:seen:"+tok.Nonce+":"+event, 1, ttl).Result()
+	if err != nil {
+		s.logger.WithError(err).Warn("Replay check unavailable, accepting token")
+		return nil
+	}
+	if !first {
+		return ErrReplayed
+	}
+	return nil
+}
+
+func mac(secret []byte, body string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
+
+func reason(err error) string {
+	switch {
+	case errors.Is(err, ErrUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, ErrBadSignature):
+		return "forged"
+	case errors.Is(err, ErrExpired):
+		return "expired"
+	case errors.Is(err, ErrAdMismatch):
+		return "ad_mismatch"
+	case errors.Is(err, ErrReplayed):
+		return "replayed"
+	default:
+		return "malformed"
+	}
+}
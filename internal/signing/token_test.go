@@ -0,0 +1,88 @@
+package signing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("k2:new-secret, k1:old-secret")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+	assert.Equal(t, []byte("old-secret"), keys[1].Secret)
+
+	_, err = ParseKeys("missing-secret")
+	assert.Error(t, err)
+}
+
+func TestSignVerify_KeyRotation(t *testing.T) {
+	oldSigner, err := NewSigner([]Key{{ID: "k1", Secret: []byte("old")}}, time.Hour, nil, NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+	tok, raw, err := oldSigner.Issue("ad-1", "viewer-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, tok.ImpressionID)
+
+	// k2 is now active, but tokens signed with k1 still verify.
+	rotated, err := NewSigner([]Key{{ID: "k2", Secret: []byte("new")}, {ID: "k1", Secret: []byte("old")}}, time.Hour, nil, NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+	got, err := rotated.Verify(raw)
+	require.NoError(t, err)
+	assert.Equal(t, tok, got)
+
+	// Once k1 is retired its tokens are rejected.
+	retired, err := NewSigner([]Key{{ID: "k2", Secret: []byte("new")}}, time.Hour, nil, NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+	_, err = retired.Verify(raw)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestVerify_Rejections(t *testing.T) {
+	signer, err := NewSigner([]Key{{ID: "k1", Secret: []byte("secret")}}, time.Minute, nil, NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+
+	_, err = signer.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	raw, err := signer.Sign(Token{AdID: "ad-1", ImpressionID: "imp", Nonce: "n", IssuedAt: time.Now().Unix()})
+	require.NoError(t, err)
+	_, err = signer.Verify(raw[:len(raw)-2] + "xx")
+	assert.ErrorIs(t, err, ErrBadSignature)
+
+	expired, err := signer.Sign(Token{AdID: "ad-1", ImpressionID: "imp", Nonce: "n", IssuedAt: time.Now().Add(-time.Hour).Unix()})
+	require.NoError(t, err)
+	_, err = signer.Verify(expired)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	_, err = signer.Validate(context.Background(), raw, "ad-2", "click", false)
+	assert.ErrorIs(t, err, ErrAdMismatch)
+}
+
+func TestValidate_Replay(t *testing.T) {
+	s := miniredis.RunT(t)
+	signer, err := NewSigner([]Key{{ID: "k1", Secret: []byte("secret")}}, time.Hour, redis.NewClient(&redis.Options{Addr: s.Addr()}), NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+	_, raw, err := signer.Issue("ad-1", "viewer-1")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = signer.Validate(ctx, raw, "ad-1", "click", true)
+	require.NoError(t, err)
+	_, err = signer.Validate(ctx, raw, "ad-1", "click", true)
+	assert.ErrorIs(t, err, ErrReplayed)
+
+	// Other events on the same token, and repeatable events, are still accepted.
+	_, err = signer.Validate(ctx, raw, "ad-1", "complete", true)
+	assert.NoError(t, err)
+	_, err = signer.Validate(ctx, raw, "ad-1", "pause", false)
+	assert.NoError(t, err)
+	_, err = signer.Validate(ctx, raw, "ad-1", "pause", false)
+	assert.NoError(t, err)
+}
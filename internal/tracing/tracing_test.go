@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func record() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	tp, recorder := record()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(tp))
+	r.GET("/ads/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ads/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /ads/:id", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestInjectExtract(t *testing.T) {
+	tp, _ := record()
+	assert.Nil(t, Inject(context.Background()), "no span, nothing to carry")
+
+	ctx, span := Tracer(tp).Start(context.Background(), "enqueue")
+	defer span.End()
+	carrier := Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	remote := Extract(context.Background(), carrier)
+	assert.Equal(t, span.SpanContext().TraceID(), oteltrace.SpanContextFromContext(remote).TraceID())
+	assert.True(t, oteltrace.SpanContextFromContext(remote).IsRemote())
+}
+
+func TestRedisHook_OnlyWithinTrace(t *testing.T) {
+	tp, recorder := record()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	rdb.AddHook(RedisHook())
+
+	require.NoError(t, rdb.Incr(context.Background(), "untraced").Err())
+	assert.Empty(t, recorder.Ended())
+
+	ctx, span := Tracer(tp).Start(context.Background(), "worker.process")
+	require.NoError(t, rdb.Incr(ctx, "traced").Err())
+	pipe := rdb.TxPipeline()
+	pipe.Incr(ctx, "a")
+	pipe.Incr(ctx, "b")
+	_, err := pipe.Exec(ctx)
+	require.NoError(t, err)
+	span.End()
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+		if s.Name() != "worker.process" {
+			assert.Equal(t, span.SpanContext().SpanID(), s.Parent().SpanID())
+		}
+	}
+	assert.Equal(t, []string{"redis incr", "redis pipeline", "worker.process"}, names)
+}
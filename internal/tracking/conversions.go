@@ -0,0 +1,92 @@
+package tracking
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/conversions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HandleConversion records an advertiser conversion. POST /conversions is the
+// server-to-server postback (JSON or form body); GET /conversions is the
+// pixel variant for landing pages and answers with a GIF. Either must name
+// the clickId passed to the target URL or a viewerId; the pixel falls back to
+// the vid cookie. Attribution happens in the worker.
+func (h *PixelHandler) HandleConversion(c *gin.Context) {
+	pixel := c.Request.Method == http.MethodGet
+
+	params, err := trackingParams(c)
+	if err != nil {
+		h.rejectConversion(c, pixel, "Invalid conversion payload")
+		return
+	}
+
+	conv := clicks.ConversionEvent{
+		ClickID:   params.Get("clickId"),
+		ViewerID:  params.Get("viewerId"),
+		OrderID:   params.Get("orderId"),
+		Timestamp: time.Now(),
+		IPAddress: c.ClientIP(),
+	}
+	if conv.ViewerID == "" && pixel {
+		conv.ViewerID, _ = c.Cookie(ads.ViewerCookie)
+	}
+	if conv.ClickID == "" && conv.ViewerID == "" {
+		h.rejectConversion(c, pixel, "Missing clickId or viewerId")
+		return
+	}
+	if conv.ClickID != "" {
+		if _, err := uuid.Parse(conv.ClickID); err != nil {
+			h.rejectConversion(c, pixel, "Invalid clickId")
+			return
+		}
+	}
+	if v := params.Get("value"); v != "" {
+		conv.Value, err = strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(conv.Value) || math.IsInf(conv.Value, 0) || conv.Value < 0 {
+			h.rejectConversion(c, pixel, "Invalid value")
+			return
+		}
+	}
+	conv.ID = conversions.NewID(conv.OrderID)
+
+	_, err = clicks.Enqueue(c.Request.Context(), h.Queue, h.Fallback, clicks.RetryableClick{
+		Kind:       clicks.KindConversion,
+		Conversion: &conv,
+	}, h.Logger)
+	if err != nil {
+		if pixel {
+			respond(c, http.StatusServiceUnavailable)
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to record conversion"})
+		return
+	}
+
+	h.Logger.WithFields(map[string]interface{}{
+		"conversionId": conv.ID,
+		"clickId":      conv.ClickID,
+		"viewerId":     conv.ViewerID,
+	}).Info("Received conversion")
+
+	if pixel {
+		respond(c, http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": conv.ID})
+}
+
+func (h *PixelHandler) rejectConversion(c *gin.Context, pixel bool, msg string) {
+	h.Logger.WithField("path", c.Request.URL.Path).Warn(msg)
+	if pixel {
+		respond(c, http.StatusBadRequest)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+}
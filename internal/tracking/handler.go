@@ -0,0 +1,254 @@
+package tracking
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/events"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBeaconBody caps how much of a sendBeacon body is read.
+const maxBeaconBody = 64 << 10
+
+// transparentGIF is a 1x1 transparent GIF89a.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// PixelHandler serves GET pixels and sendBeacon POSTs and feeds them into the
+// event queue. Every request must carry the signed token issued with the ad in "tk".
+type PixelHandler struct {
+	// Impressions holds the impression records clicks are checked against.
+	Impressions *redis.Client
+	Queue       queue.Queue
+	Fallback    *clicks.Fallback
+	Tokens      *signing.Signer
+	Logger      logrus.FieldLogger
+}
+
+// HandleImpression records an impression from /t/imp.
+func (h *PixelHandler) HandleImpression(c *gin.Context) {
+	params, err := trackingParams(c)
+	if err != nil || params.Get("adId") == "" {
+		h.Logger.WithError(err).Warn("Invalid impression pixel")
+		respond(c, http.StatusBadRequest)
+		return
+	}
+
+	tok, err := h.Tokens.Validate(c.Request.Context(), params.Get("tk"), params.Get("adId"), "impression", true)
+	if err != nil {
+		h.Logger.WithError(err).WithField("adId", params.Get("adId")).Warn("Rejected impression pixel")
+		respond(c, http.StatusForbidden)
+		return
+	}
+
+	if err := h.enqueueImpression(c, params, tok); err != nil {
+		respond(c, http.StatusServiceUnavailable)
+		return
+	}
+	respond(c, http.StatusOK)
+}
+
+// HandleEvent records a player event from /t/evt. Clicks are queued exactly
+// as POST /ads/click would queue them; video playback and interaction
+// events are queued as their own kind.
+func (h *PixelHandler) HandleEvent(c *gin.Context) {
+	params, err := trackingParams(c)
+	if err != nil || params.Get("adId") == "" || params.Get("event") == "" {
+		h.Logger.WithError(err).Warn("Invalid event pixel")
+		respond(c, http.StatusBadRequest)
+		return
+	}
+
+	event := params.Get("event")
+	tok, err := h.Tokens.Validate(c.Request.Context(), params.Get("tk"), params.Get("adId"), event, !events.IsRepeatable(event))
+	if err != nil {
+		h.Logger.WithError(err).WithFields(map[string]interface{}{
+			"adId":  params.Get("adId"),
+			"event": event,
+		}).Warn("Rejected event pixel")
+		respond(c, http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case event == "impression":
+		err = h.enqueueImpression(c, params, tok)
+	case event == "click":
+		err = h.enqueueClick(c, params, tok)
+	case events.IsVideoEvent(event):
+		err = h.enqueueVideo(c, params)
+	default:
+		h.Logger.WithFields(map[string]interface{}{
+			"adId":  params.Get("adId"),
+			"event": event,
+			"code":  params.Get("code"),
+		}).Info("Received tracking event")
+	}
+	if err != nil {
+		respond(c, http.StatusServiceUnavailable)
+		return
+	}
+	respond(c, http.StatusOK)
+}
+
+// HandleImpressionJSON records an impression posted as JSON to /ads/impression.
+func (h *PixelHandler) HandleImpressionJSON(c *gin.Context) {
+	var payload struct {
+		AdID  string `json:"ad_id" binding:"required"`
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid ad_id or token"})
+		return
+	}
+
+	tok, err := h.Tokens.Validate(c.Request.Context(), payload.Token, payload.AdID, "impression", true)
+	if err != nil {
+		h.Logger.WithError(err).WithField("adId", payload.AdID).Warn("Rejected impression")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or reused tracking token"})
+		return
+	}
+
+	if err := h.enqueueImpression(c, url.Values{"adId": {payload.AdID}}, tok); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to record impression"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// enqueueImpression records the impression ID in Redis right away, so that a
+// click arriving before the worker catches up can still be attributed, and
+// queues the impression for persistence. An error means the impression could
+// be written neither to the queue nor to the fallback file.
+func (h *PixelHandler) enqueueImpression(c *gin.Context, params url.Values, tok signing.Token) error {
+	event := clicks.ImpressionEvent{
+		ID:           tok.ImpressionID,
+		AdID:         params.Get("adId"),
+		ViewerID:     tok.ViewerID,
+		ExperimentID: tok.ExperimentID,
+		Arm:          tok.Arm,
+		Timestamp:    time.Now(),
+		IPAddress:    c.ClientIP(),
+	}
+	if err := clicks.RecordImpression(c.Request.Context(), h.Impressions, event); err != nil {
+		h.Logger.WithError(err).WithField("impressionId", event.ID).Warn("Failed to record impression for attribution")
+	}
+	_, err := clicks.Enqueue(c.Request.Context(), h.Queue, h.Fallback, clicks.RetryableClick{
+		Kind:       clicks.KindImpression,
+		Impression: &event,
+	}, h.Logger)
+	return err
+}
+
+func (h *PixelHandler) enqueueClick(c *gin.Context, params url.Values, tok signing.Token) error {
+	playback, _ := strconv.ParseFloat(params.Get("videoPlaybackTime"), 64)
+	event := clicks.ClickEvent{
+		ID:                uuid.New().String(),
+		AdID:              params.Get("adId"),
+		ImpressionID:      tok.ImpressionID,
+		ViewerID:          tok.ViewerID,
+		ExperimentID:      tok.ExperimentID,
+		Arm:               tok.Arm,
+		Timestamp:         time.Now(),
+		IPAddress:         c.ClientIP(),
+		VideoPlaybackTime: playback,
+		UserAgent:         c.Request.UserAgent(),
+	}
+	_, err := clicks.Enqueue(c.Request.Context(), h.Queue, h.Fallback, clicks.RetryableClick{
+		Kind:  clicks.KindClick,
+		Event: event,
+	}, h.Logger)
+	return err
+}
+
+func (h *PixelHandler) enqueueVideo(c *gin.Context, params url.Values) error {
+	playback, _ := strconv.ParseFloat(params.Get("videoPlaybackTime"), 64)
+	event := clicks.VideoEvent{
+		ID:           uuid.New().String(),
+		AdID:         params.Get("adId"),
+		Type:         params.Get("event"),
+		Timestamp:    time.Now(),
+		IPAddress:    c.ClientIP(),
+		PlaybackTime: playback,
+	}
+	_, err := clicks.Enqueue(c.Request.Context(), h.Queue, h.Fallback, clicks.RetryableClick{
+		Kind:  clicks.KindVideo,
+		Video: &event,
+	}, h.Logger)
+	return err
+}
+
+// trackingParams merges query-string parameters with a sendBeacon body.
+// Beacon bodies arrive as text/plain and may be either a query string or a
+// flat JSON object; body values take precedence over the query string.
+func trackingParams(c *gin.Context) (url.Values, error) {
+	params := c.Request.URL.Query()
+	if c.Request.Method != http.MethodPost || c.Request.Body == nil {
+		return params, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBeaconBody))
+	if err != nil {
+		return nil, err
+	}
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return params, nil
+	}
+
+	if body[0] == '{' {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			switch val := v.(type) {
+			case string:
+				params.Set(k, val)
+			case float64:
+				params.Set(k, strconv.FormatFloat(val, 'f', -1, 64))
+			case bool:
+				params.Set(k, strconv.FormatBool(val))
+			}
+		}
+		return params, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range form {
+		params[k] = v
+	}
+	return params, nil
+}
+
+// respond answers GET pixels with a GIF and beacons with 204 No Content.
+func respond(c *gin.Context, status int) {
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate")
+	c.Header("Access-Control-Allow-Origin", "*")
+	if c.Request.Method == http.MethodPost || c.Query("fmt") == "204" {
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		c.Status(status)
+		return
+	}
+	c.Data(status, "image/gif", transparentGIF)
+}
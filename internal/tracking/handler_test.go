@@ -0,0 +1,265 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSigner *signing.Signer
+
+func setupRouter(t *testing.T) (*gin.Engine, *miniredis.Miniredis) {
+	gin.SetMode(gin.TestMode)
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	var err error
+	testSigner, err = signing.NewSigner([]signing.Key{{ID: "test", Secret: []byte("secret")}}, time.Hour, rdb, signing.NewMetrics(), logs.Discard())
+	require.NoError(t, err)
+	h := &PixelHandler{Impressions: rdb, Queue: queue.NewRedisList(rdb, "click", time.Minute), Tokens: testSigner, Logger: logs.Discard()}
+
+	r := gin.New()
+	r.GET("/t/imp", h.HandleImpression)
+	r.POST("/t/imp", h.HandleImpression)
+	r.GET("/t/evt", h.HandleEvent)
+	r.POST("/t/evt", h.HandleEvent)
+	r.POST("/conversions", h.HandleConversion)
+	r.GET("/conversions", h.HandleConversion)
+	return r, s
+}
+
+func token(t *testing.T, adID string) string {
+	_, raw, err := testSigner.Issue(adID, "viewer-1")
+	require.NoError(t, err)
+	return raw
+}
+
+func queued(t *testing.T, s *miniredis.Miniredis) []clicks.RetryableClick {
+	items, err := s.List("click_queue")
+	if err == miniredis.ErrKeyNotFound {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var out []clicks.RetryableClick
+	for _, item := range items {
+		var w clicks.RetryableClick
+		require.NoError(t, json.Unmarshal([]byte(item), &w))
+		out = append(out, w)
+	}
+	return out
+}
+
+func TestImpressionPixel_ReturnsGIF(t *testing.T) {
+	r, s := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	tk := token(t, "ad-1")
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/imp?adId=ad-1&tk="+tk, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+	assert.Equal(t, transparentGIF, w.Body.Bytes())
+
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, clicks.KindImpression, events[0].Kind)
+	assert.Equal(t, "ad-1", events[0].Impression.AdID)
+	assert.Equal(t, "ad-1", s.HGet("impression:"+events[0].Impression.ID, "adId"))
+
+	// The same token cannot record a second impression.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/imp?adId=ad-1&tk="+tk, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, queued(t, s), 1)
+}
+
+func TestEventBeacon_TextPlainBody(t *testing.T) {
+	r, s := setupRouter(t)
+
+	tok, tk, err := testSigner.Issue("ad-2", "viewer-1")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/t/evt", strings.NewReader("adId=ad-2&event=click&videoPlaybackTime=4.5&tk="+tk))
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, clicks.KindClick, events[0].Kind)
+	assert.Equal(t, "ad-2", events[0].Event.AdID)
+	assert.Equal(t, 4.5, events[0].Event.VideoPlaybackTime)
+	assert.Equal(t, tok.ImpressionID, events[0].Event.ImpressionID)
+	assert.NotEmpty(t, events[0].Event.IPAddress)
+}
+
+func TestEventBeacon_JSONBody(t *testing.T) {
+	r, s := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/t/evt?adId=ad-3", strings.NewReader(`{"event":"click","timestamp":1751481000000,"tk":"`+token(t, "ad-3")+`"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, "ad-3", events[0].Event.AdID)
+	assert.WithinDuration(t, time.Now(), events[0].Event.Timestamp, time.Minute, "the client's timestamp is ignored")
+}
+
+func TestEventPixel_MissingAdID(t *testing.T) {
+	r, s := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/evt?event=click", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, queued(t, s))
+}
+
+func TestEventPixel_VideoEvent(t *testing.T) {
+	r, s := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/evt?adId=ad-4&event=midpoint&videoPlaybackTime=7.5&tk="+token(t, "ad-4"), nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, clicks.KindVideo, events[0].Kind)
+	require.NotNil(t, events[0].Video)
+	assert.Equal(t, "midpoint", events[0].Video.Type)
+	assert.Equal(t, 7.5, events[0].Video.PlaybackTime)
+}
+
+func TestEventPixel_TokenForOtherAd(t *testing.T) {
+	r, s := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/evt?adId=ad-5&event=start&tk="+token(t, "ad-6"), nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, queued(t, s))
+}
+
+// failingQueue rejects every enqueue, standing in for an unreachable Redis.
+type failingQueue struct{ queue.Queue }
+
+func (failingQueue) Enqueue(context.Context, []byte) error { return errors.New("queue down") }
+
+func TestPixels_QueueAndFallbackFail(t *testing.T) {
+	r, s := setupRouter(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	h := &PixelHandler{
+		Impressions: rdb,
+		Queue:       failingQueue{},
+		Fallback:    clicks.NewFallback(filepath.Join(t.TempDir(), "missing", "fallback.jsonl")),
+		Tokens:      testSigner,
+		Logger:      logs.Discard(),
+	}
+	r.GET("/failing/imp", h.HandleImpression)
+	r.GET("/failing/evt", h.HandleEvent)
+
+	for _, target := range []string{
+		"/failing/imp?adId=ad-7&tk=" + token(t, "ad-7"),
+		"/failing/evt?adId=ad-7&event=click&tk=" + token(t, "ad-7"),
+		"/failing/evt?adId=ad-7&event=start&tk=" + token(t, "ad-7"),
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, target)
+		assert.Equal(t, "image/gif", w.Header().Get("Content-Type"), target)
+	}
+}
+
+func TestConversionPostback_ByClickID(t *testing.T) {
+	r, s := setupRouter(t)
+
+	clickID := uuid.New().String()
+	body := `{"clickId":"` + clickID + `","orderId":"order-42","value":19.99}`
+	req := httptest.NewRequest(http.MethodPost, "/conversions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, clicks.KindConversion, events[0].Kind)
+	require.NotNil(t, events[0].Conversion)
+	assert.Equal(t, clickID, events[0].Conversion.ClickID)
+	assert.Equal(t, 19.99, events[0].Conversion.Value)
+
+	// A repeated postback for the same order maps to the same conversion ID.
+	req = httptest.NewRequest(http.MethodPost, "/conversions", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	events = queued(t, s)
+	require.Len(t, events, 2)
+	assert.Equal(t, events[0].Conversion.ID, events[1].Conversion.ID)
+}
+
+func TestConversionPixel_ViewerCookie(t *testing.T) {
+	r, s := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/conversions?value=5", nil)
+	req.AddCookie(&http.Cookie{Name: "vid", Value: "viewer-9"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+	events := queued(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, "viewer-9", events[0].Conversion.ViewerID)
+	assert.NotEqual(t, events[0].Conversion.ID, "")
+}
+
+func TestConversionPostback_Invalid(t *testing.T) {
+	r, s := setupRouter(t)
+
+	for _, body := range []string{`{"value":3}`, `{"clickId":"not-a-uuid"}`, `{"viewerId":"v","value":"lots"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/conversions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Empty(t, queued(t, s))
+}
+
+func TestConversionPostback_InvalidValue(t *testing.T) {
+	r, s := setupRouter(t)
+
+	for _, value := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "1e999", "-1", "-0.01", "lots"} {
+		req := httptest.NewRequest(http.MethodPost, "/conversions", strings.NewReader("viewerId=v&value="+url.QueryEscape(value)))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, value)
+	}
+	assert.Empty(t, queued(t, s))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/conversions", strings.NewReader("viewerId=v&value=0")))
+	assert.Equal(t, http.StatusAccepted, w.Code, "a zero value is allowed")
+}
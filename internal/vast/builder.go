@@ -0,0 +1,132 @@
+package vast
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/events"
+)
+
+const (
+	adSystemName = "video-ad-tracker"
+
+	// Ads do not carry their own duration or dimensions yet, so every
+	// creative is described with the same defaults the demo player uses.
+	defaultDuration   = 30 * time.Second
+	defaultSkipOffset = 5 * time.Second
+	defaultWidth      = 640
+	defaultHeight     = 360
+)
+
+// TrackedEvents are the linear tracking events advertised in every InLine response.
+var TrackedEvents = events.VideoEventTypes
+
+// TrackingURLs builds absolute tracking URLs that point back at this service.
+// Token is the signed tracking token issued for the served ad and is carried
+// on every URL.
+type TrackingURLs struct {
+	BaseURL string
+	Token   string
+}
+
+func (t TrackingURLs) Impression(adID string) string {
+	return t.build("/t/imp", url.Values{"adId": {adID}, "tk": {t.Token}})
+}
+
+func (t TrackingURLs) Event(adID, event string) string {
+	return t.build("/t/evt", url.Values{"adId": {adID}, "event": {event}, "tk": {t.Token}})
+}
+
+// ClickThrough is the redirect that records the click and forwards the viewer to the ad's target.
+func (t TrackingURLs) ClickThrough() string {
+	return t.Absolute(ads.ClickPath(t.Token))
+}
+
+func (t TrackingURLs) Error(adID string) string {
+	return t.build("/t/evt", url.Values{"adId": {adID}, "event": {"error"}, "code": {"[ERRORCODE]"}, "tk": {t.Token}})
+}
+
+// Absolute resolves a path such as an ad's video_url against the base URL.
+func (t TrackingURLs) Absolute(p string) string {
+	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		return p
+	}
+	return strings.TrimRight(t.BaseURL, "/") + "/" + strings.TrimLeft(p, "/")
+}
+
+func (t TrackingURLs) build(p string, q url.Values) string {
+	// VAST macros such as [ERRORCODE] must survive encoding so players can expand them.
+	encoded := strings.NewReplacer("%5B", "[", "%5D", "]").Replace(q.Encode())
+	return t.Absolute(p) + "?" + encoded
+}
+
+// BuildInLine renders a single ad as a VAST InLine document.
+func BuildInLine(ad ads.Ad, urls TrackingURLs) *VAST {
+	tracking := make([]Tracking, 0, len(TrackedEvents))
+	for _, event := range TrackedEvents {
+		tracking = append(tracking, Tracking{Event: event, URI: urls.Event(ad.ID, event)})
+	}
+
+	return &VAST{
+		Version: Version,
+		Ads: []Ad{{
+			ID: ad.ID,
+			InLine: &InLine{
+				AdSystem:    AdSystem{Name: adSystemName},
+				AdTitle:     ad.ID,
+				AdServingID: ad.ID,
+				Impressions: []Impression{{ID: ad.ID, URI: urls.Impression(ad.ID)}},
+				Errors:      []URI{{URI: urls.Error(ad.ID)}},
+				Creatives: []Creative{{
+					ID:   ad.ID,
+					AdID: ad.ID,
+					Linear: &Linear{
+						SkipOffset:     FormatDuration(defaultSkipOffset),
+						Duration:       FormatDuration(defaultDuration),
+						TrackingEvents: tracking,
+						// The click redirect records the click itself, so no
+						// separate ClickTracking URL is advertised.
+						VideoClicks: &VideoClicks{
+							ClickThrough: &VideoClick{ID: ad.ID, URI: urls.ClickThrough()},
+						},
+						MediaFiles: []MediaFile{{
+							ID:       ad.ID,
+							Delivery: "progressive",
+							Type:     mimeType(ad.VideoURL),
+							Width:    defaultWidth,
+							Height:   defaultHeight,
+							URI:      urls.Absolute(ad.VideoURL),
+						}},
+					},
+				}},
+			},
+		}},
+	}
+}
+
+// FormatDuration formats d as the HH:MM:SS.mmm timecode VAST expects.
+func FormatDuration(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+func mimeType(videoURL string) string {
+	u, err := url.Parse(videoURL)
+	if err == nil {
+		videoURL = u.Path
+	}
+	switch strings.ToLower(path.Ext(videoURL)) {
+	case ".webm":
+		return "video/webm"
+	case ".ogv", ".ogg":
+		return "video/ogg"
+	case ".m3u8":
+		return "application/x-mpegURL"
+	default:
+		return "video/mp4"
+	}
+}
@@ -0,0 +1,57 @@
+package vast
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/signing"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// GetVASTHandler serves the selected ad (adId query param, or one picked by
+// strategy among the viewer's eligible ads) as a VAST InLine document whose
+// tracking URLs point at baseURL, the service's public scheme and host.
+func GetVASTHandler(db *pgxpool.Pool, signer *signing.Signer, strategy ads.Strategy, baseURL string, logger logrus.FieldLogger) gin.HandlerFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return func(c *gin.Context) {
+		logger := logger.WithField("path", "/vast")
+
+		viewerID := ads.ViewerID(c)
+		ad, err := ads.SelectAdForViewer(c.Request.Context(), db, c.Query("adId"), viewerID, strategy, logger)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// An empty VAST response is the spec's way of saying "no ad".
+			c.XML(http.StatusOK, &VAST{Version: Version})
+			return
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to select ad for VAST")
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		_, token, err := signer.IssueClaims(ad.Claims(viewerID))
+		if err != nil {
+			logger.WithError(err).WithField("adId", ad.ID).Error("Failed to issue tracking token")
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		doc := BuildInLine(ad, TrackingURLs{BaseURL: baseURL, Token: token})
+		body, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			logger.WithError(err).WithField("adId", ad.ID).Error("Failed to render VAST")
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		logger.WithField("adId", ad.ID).Info("Served VAST ad")
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+	}
+}
@@ -0,0 +1,95 @@
+package vast
+
+import "encoding/xml"
+
+// Version is the VAST version emitted by this service.
+const Version = "4.2"
+
+type VAST struct {
+	XMLName xml.Name `xml:"VAST"`
+	Version string   `xml:"version,attr"`
+	Ads     []Ad     `xml:"Ad"`
+	Errors  []URI    `xml:"Error,omitempty"`
+}
+
+type Ad struct {
+	ID       string   `xml:"id,attr,omitempty"`
+	Sequence int      `xml:"sequence,attr,omitempty"`
+	InLine   *InLine  `xml:"InLine,omitempty"`
+	Wrapper  *Wrapper `xml:"Wrapper,omitempty"`
+}
+
+type AdSystem struct {
+	Version string `xml:"version,attr,omitempty"`
+	Name    string `xml:",chardata"`
+}
+
+type InLine struct {
+	AdSystem    AdSystem     `xml:"AdSystem"`
+	AdTitle     string       `xml:"AdTitle"`
+	AdServingID string       `xml:"AdServingId,omitempty"`
+	Impressions []Impression `xml:"Impression"`
+	Errors      []URI        `xml:"Error,omitempty"`
+	Creatives   []Creative   `xml:"Creatives>Creative"`
+}
+
+type Wrapper struct {
+	FollowAdditionalWrappers string       `xml:"followAdditionalWrappers,attr,omitempty"`
+	AllowMultipleAds         string       `xml:"allowMultipleAds,attr,omitempty"`
+	AdSystem                 AdSystem     `xml:"AdSystem"`
+	VASTAdTagURI             URI          `xml:"VASTAdTagURI"`
+	Impressions              []Impression `xml:"Impression"`
+	Errors                   []URI        `xml:"Error,omitempty"`
+	Creatives                []Creative   `xml:"Creatives>Creative,omitempty"`
+}
+
+type Impression struct {
+	ID  string `xml:"id,attr,omitempty"`
+	URI string `xml:",cdata"`
+}
+
+// URI is a CDATA-wrapped URL, the form VAST uses for every tracking resource.
+type URI struct {
+	URI string `xml:",cdata"`
+}
+
+type Creative struct {
+	ID     string  `xml:"id,attr,omitempty"`
+	AdID   string  `xml:"adId,attr,omitempty"`
+	Linear *Linear `xml:"Linear,omitempty"`
+}
+
+type Linear struct {
+	SkipOffset     string       `xml:"skipoffset,attr,omitempty"`
+	Duration       string       `xml:"Duration,omitempty"`
+	TrackingEvents []Tracking   `xml:"TrackingEvents>Tracking,omitempty"`
+	VideoClicks    *VideoClicks `xml:"VideoClicks,omitempty"`
+	MediaFiles     []MediaFile  `xml:"MediaFiles>MediaFile,omitempty"`
+}
+
+type Tracking struct {
+	Event  string `xml:"event,attr"`
+	Offset string `xml:"offset,attr,omitempty"`
+	URI    string `xml:",cdata"`
+}
+
+type VideoClicks struct {
+	ClickThrough  *VideoClick  `xml:"ClickThrough,omitempty"`
+	ClickTracking []VideoClick `xml:"ClickTracking,omitempty"`
+	CustomClick   []VideoClick `xml:"CustomClick,omitempty"`
+}
+
+type VideoClick struct {
+	ID  string `xml:"id,attr,omitempty"`
+	URI string `xml:",cdata"`
+}
+
+type MediaFile struct {
+	ID       string `xml:"id,attr,omitempty"`
+	Delivery string `xml:"delivery,attr"`
+	Type     string `xml:"type,attr"`
+	Width    int    `xml:"width,attr"`
+	Height   int    `xml:"height,attr"`
+	Bitrate  int    `xml:"bitrate,attr,omitempty"`
+	URI      string `xml:",cdata"`
+}
@@ -0,0 +1,169 @@
+package vast
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxWrapperDepth bounds how many Wrapper hops Unwrap follows before giving up.
+const MaxWrapperDepth = 5
+
+var (
+	ErrNoAds           = errors.New("vast: document contains no ads")
+	ErrTooManyWrappers = errors.New("vast: wrapper chain too deep")
+	ErrMissingAdTagURI = errors.New("vast: wrapper has no VASTAdTagURI")
+)
+
+// Fetcher retrieves the VAST document referenced by a Wrapper's VASTAdTagURI.
+type Fetcher func(uri string) ([]byte, error)
+
+// Parse decodes a VAST document, trimming the whitespace that usually
+// surrounds CDATA sections in third-party responses.
+func Parse(data []byte) (*VAST, error) {
+	var doc VAST
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("vast: decode: %w", err)
+	}
+	for i := range doc.Errors {
+		doc.Errors[i].URI = strings.TrimSpace(doc.Errors[i].URI)
+	}
+	for i := range doc.Ads {
+		ad := &doc.Ads[i]
+		if ad.InLine != nil {
+			ad.InLine.AdSystem.Name = strings.TrimSpace(ad.InLine.AdSystem.Name)
+			ad.InLine.AdTitle = strings.TrimSpace(ad.InLine.AdTitle)
+			trimImpressions(ad.InLine.Impressions)
+			trimURIs(ad.InLine.Errors)
+			trimCreatives(ad.InLine.Creatives)
+		}
+		if ad.Wrapper != nil {
+			ad.Wrapper.AdSystem.Name = strings.TrimSpace(ad.Wrapper.AdSystem.Name)
+			ad.Wrapper.VASTAdTagURI.URI = strings.TrimSpace(ad.Wrapper.VASTAdTagURI.URI)
+			trimImpressions(ad.Wrapper.Impressions)
+			trimURIs(ad.Wrapper.Errors)
+			trimCreatives(ad.Wrapper.Creatives)
+		}
+	}
+	return &doc, nil
+}
+
+// Unwrap follows Wrapper responses until it reaches an InLine ad. The
+// impression, error and tracking URLs collected from every wrapper along
+// the way are merged into the returned InLine so that all parties are pinged.
+func Unwrap(doc *VAST, fetch Fetcher) (*Ad, error) {
+	var (
+		impressions []Impression
+		errs        []URI
+		tracking    []Tracking
+		clicks      []VideoClick
+	)
+
+	for depth := 0; ; depth++ {
+		if len(doc.Ads) == 0 {
+			return nil, ErrNoAds
+		}
+		ad := doc.Ads[0]
+
+		if ad.InLine != nil {
+			inline := *ad.InLine
+			inline.Impressions = append(append([]Impression{}, inline.Impressions...), impressions...)
+			inline.Errors = append(append([]URI{}, inline.Errors...), errs...)
+			inline.Creatives = mergeCreatives(inline.Creatives, tracking, clicks)
+			ad.InLine = &inline
+			return &ad, nil
+		}
+
+		if ad.Wrapper == nil {
+			return nil, fmt.Errorf("vast: ad %q is neither InLine nor Wrapper", ad.ID)
+		}
+		if depth >= MaxWrapperDepth {
+			return nil, ErrTooManyWrappers
+		}
+		if ad.Wrapper.VASTAdTagURI.URI == "" {
+			return nil, ErrMissingAdTagURI
+		}
+
+		impressions = append(impressions, ad.Wrapper.Impressions...)
+		errs = append(errs, ad.Wrapper.Errors...)
+		for _, c := range ad.Wrapper.Creatives {
+			if c.Linear == nil {
+				continue
+			}
+			tracking = append(tracking, c.Linear.TrackingEvents...)
+			if c.Linear.VideoClicks != nil {
+				clicks = append(clicks, c.Linear.VideoClicks.ClickTracking...)
+			}
+		}
+
+		data, err := fetch(ad.Wrapper.VASTAdTagURI.URI)
+		if err != nil {
+			return nil, fmt.Errorf("vast: fetch %s: %w", ad.Wrapper.VASTAdTagURI.URI, err)
+		}
+		if doc, err = Parse(data); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func mergeCreatives(creatives []Creative, tracking []Tracking, clicks []VideoClick) []Creative {
+	if len(tracking) == 0 && len(clicks) == 0 {
+		return creatives
+	}
+	merged := make([]Creative, len(creatives))
+	for i, c := range creatives {
+		merged[i] = c
+		if c.Linear == nil {
+			continue
+		}
+		linear := *c.Linear
+		linear.TrackingEvents = append(append([]Tracking{}, linear.TrackingEvents...), tracking...)
+		vc := VideoClicks{}
+		if linear.VideoClicks != nil {
+			vc = *linear.VideoClicks
+		}
+		vc.ClickTracking = append(append([]VideoClick{}, vc.ClickTracking...), clicks...)
+		linear.VideoClicks = &vc
+		merged[i].Linear = &linear
+	}
+	return merged
+}
+
+func trimImpressions(imps []Impression) {
+	for i := range imps {
+		imps[i].URI = strings.TrimSpace(imps[i].URI)
+	}
+}
+
+func trimURIs(uris []URI) {
+	for i := range uris {
+		uris[i].URI = strings.TrimSpace(uris[i].URI)
+	}
+}
+
+func trimCreatives(creatives []Creative) {
+	for _, c := range creatives {
+		if c.Linear == nil {
+			continue
+		}
+		c.Linear.Duration = strings.TrimSpace(c.Linear.Duration)
+		for i := range c.Linear.TrackingEvents {
+			c.Linear.TrackingEvents[i].URI = strings.TrimSpace(c.Linear.TrackingEvents[i].URI)
+		}
+		for i := range c.Linear.MediaFiles {
+			c.Linear.MediaFiles[i].URI = strings.TrimSpace(c.Linear.MediaFiles[i].URI)
+		}
+		if vc := c.Linear.VideoClicks; vc != nil {
+			if vc.ClickThrough != nil {
+				vc.ClickThrough.URI = strings.TrimSpace(vc.ClickThrough.URI)
+			}
+			for i := range vc.ClickTracking {
+				vc.ClickTracking[i].URI = strings.TrimSpace(vc.ClickTracking[i].URI)
+			}
+			for i := range vc.CustomClick {
+				vc.CustomClick[i].URI = strings.TrimSpace(vc.CustomClick[i].URI)
+			}
+		}
+	}
+}
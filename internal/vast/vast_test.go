@@ -0,0 +1,137 @@
+package vast
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAd = ads.Ad{
+	ID:        "11111111-1111-1111-1111-111111111111",
+	VideoURL:  "/assets/ads/ad1.mp4",
+	TargetURL: "https://google.com",
+}
+
+func TestBuildInLine_RoundTrip(t *testing.T) {
+	urls := TrackingURLs{BaseURL: "http://localhost:8080", Token: "k1.payload.sig"}
+	data, err := xml.Marshal(BuildInLine(testAd, urls))
+	require.NoError(t, err)
+
+	doc, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, doc.Ads, 1)
+	assert.Equal(t, Version, doc.Version)
+
+	inline := doc.Ads[0].InLine
+	require.NotNil(t, inline)
+	assert.Equal(t, urls.Impression(testAd.ID), inline.Impressions[0].URI)
+
+	linear := inline.Creatives[0].Linear
+	require.NotNil(t, linear)
+	assert.Equal(t, "00:00:30.000", linear.Duration)
+	assert.Equal(t, "http://localhost:8080/assets/ads/ad1.mp4", linear.MediaFiles[0].URI)
+	assert.Equal(t, "video/mp4", linear.MediaFiles[0].Type)
+	assert.Equal(t, "http://localhost:8080/c/k1.payload.sig", linear.VideoClicks.ClickThrough.URI)
+	assert.Len(t, linear.TrackingEvents, len(TrackedEvents))
+	assert.Equal(t, "http://localhost:8080/t/evt?adId="+testAd.ID+"&event=firstQuartile&tk=k1.payload.sig", linear.TrackingEvents[1].URI)
+	assert.Contains(t, inline.Errors[0].URI, "[ERRORCODE]")
+}
+
+const wrapperXML = `<?xml version="1.0" encoding="UTF-8"?>
+<VAST version="4.2">
+  <Ad id="wrapped">
+    <Wrapper followAdditionalWrappers="true">
+      <AdSystem>partner</AdSystem>
+      <VASTAdTagURI>
+        <![CDATA[ http://localhost:8080/vast?adId=11111111-1111-1111-1111-111111111111 ]]>
+      </VASTAdTagURI>
+      <Impression><![CDATA[https://partner.example/imp]]></Impression>
+      <Creatives>
+        <Creative>
+          <Linear>
+            <TrackingEvents>
+              <Tracking event="complete"><![CDATA[https://partner.example/complete]]></Tracking>
+            </TrackingEvents>
+            <VideoClicks>
+              <ClickTracking><![CDATA[https://partner.example/click]]></ClickTracking>
+            </VideoClicks>
+          </Linear>
+        </Creative>
+      </Creatives>
+    </Wrapper>
+  </Ad>
+</VAST>`
+
+func TestParseWrapper(t *testing.T) {
+	doc, err := Parse([]byte(wrapperXML))
+	require.NoError(t, err)
+	require.Len(t, doc.Ads, 1)
+
+	wrapper := doc.Ads[0].Wrapper
+	require.NotNil(t, wrapper)
+	assert.Nil(t, doc.Ads[0].InLine)
+	assert.Equal(t, "partner", wrapper.AdSystem.Name)
+	assert.Equal(t, "http://localhost:8080/vast?adId=11111111-1111-1111-1111-111111111111", wrapper.VASTAdTagURI.URI)
+	assert.Equal(t, "https://partner.example/imp", wrapper.Impressions[0].URI)
+	assert.Equal(t, "https://partner.example/complete", wrapper.Creatives[0].Linear.TrackingEvents[0].URI)
+}
+
+func TestUnwrap_MergesWrapperTracking(t *testing.T) {
+	urls := TrackingURLs{BaseURL: "http://localhost:8080", Token: "k1.payload.sig"}
+	inlineXML, err := xml.Marshal(BuildInLine(testAd, urls))
+	require.NoError(t, err)
+
+	doc, err := Parse([]byte(wrapperXML))
+	require.NoError(t, err)
+
+	var fetched string
+	ad, err := Unwrap(doc, func(uri string) ([]byte, error) {
+		fetched = uri
+		return inlineXML, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, doc.Ads[0].Wrapper.VASTAdTagURI.URI, fetched)
+
+	require.NotNil(t, ad.InLine)
+	assert.Len(t, ad.InLine.Impressions, 2)
+	linear := ad.InLine.Creatives[0].Linear
+	assert.Len(t, linear.TrackingEvents, len(TrackedEvents)+1)
+	assert.Len(t, linear.VideoClicks.ClickTracking, 1)
+}
+
+func TestUnwrap_DepthLimit(t *testing.T) {
+	doc, err := Parse([]byte(wrapperXML))
+	require.NoError(t, err)
+
+	_, err = Unwrap(doc, func(string) ([]byte, error) {
+		return []byte(wrapperXML), nil
+	})
+	assert.True(t, errors.Is(err, ErrTooManyWrappers))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "01:02:03.004", FormatDuration(time.Hour+2*time.Minute+3*time.Second+4*time.Millisecond))
+}
+
+func TestGetVASTHandler_NonUUIDAdID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/vast", GetVASTHandler(nil, nil, ads.RandomStrategy{}, "https://ads.example", logs.Discard()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vast?adId=not-a-uuid", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var doc VAST
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Empty(t, doc.Ads)
+}
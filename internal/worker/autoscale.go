@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// scaleDownSamples is how many consecutive quiet samples it takes to shrink
+// the pool. Growing takes one, so a burst is absorbed quickly while a brief
+// lull does not tear workers down.
+const scaleDownSamples = 3
+
+// sample is one observation of the pipeline.
+type sample struct {
+	size    int           // workers running
+	backlog int64         // events waiting in the queue
+	latency time.Duration // mean processing time since the previous sample
+}
+
+// scaler decides pool sizes from samples. Separate up and down thresholds
+// plus the down streak give it hysteresis.
+type scaler struct {
+	quiet int
+}
+
+// decide returns the pool size to move to and why; a size equal to s.size
+// means no change.
+func (sc *scaler) decide(cfg config.WorkerConfig, s sample) (int, string) {
+	switch {
+	case s.size < cfg.MinCount:
+		sc.quiet = 0
+		return cfg.MinCount, "min_bound"
+	case s.size > cfg.MaxCount:
+		sc.quiet = 0
+		return cfg.MaxCount, "max_bound"
+	}
+
+	perWorker := s.backlog / int64(s.size)
+	busy := perWorker > int64(cfg.ScaleUpBacklog)
+	slow := s.backlog > 0 && s.latency > cfg.LatencyTarget
+	if busy || slow {
+		sc.quiet = 0
+		step := s.size / 2
+		if step < 1 {
+			step = 1
+		}
+		target := s.size + step
+		if target > cfg.MaxCount {
+			target = cfg.MaxCount
+		}
+		if busy {
+			return target, "backlog"
+		}
+		return target, "latency"
+	}
+
+	if perWorker < int64(cfg.ScaleDownBacklog) && s.latency <= cfg.LatencyTarget {
+		sc.quiet++
+		if sc.quiet >= scaleDownSamples && s.size > cfg.MinCount {
+			sc.quiet = 0
+			return s.size - 1, "idle"
+		}
+		return s.size, ""
+	}
+	sc.quiet = 0
+	return s.size, ""
+}
+
+// autoscale samples the queue depth and processing latency every
+// ScaleInterval and resizes the pool within its bounds until ctx is
+// cancelled.
+func (p *Pool) autoscale(ctx context.Context) {
+	var sc scaler
+	p.every(ctx, "Worker autoscaler", func(c config.WorkerConfig) time.Duration { return c.ScaleInterval }, func() {
+		depth, err := p.Queue.Depth(ctx)
+		if err != nil {
+			p.Logger.WithError(err).Warn("Autoscaler could not read queue depth")
+			return
+		}
+		s := sample{size: p.Size(), backlog: depth.Ready, latency: p.takeLatency()}
+		target, reason := sc.decide(p.settings(), s)
+		if target == s.size {
+			return
+		}
+
+		direction := "up"
+		if target < s.size {
+			direction = "down"
+		}
+		p.Metrics.scalingDecisions.WithLabelValues(direction, reason).Inc()
+		p.Logger.WithFields(logrus.Fields{
+			"from":    s.size,
+			"to":      target,
+			"reason":  reason,
+			"backlog": s.backlog,
+			"latency": s.latency.String(),
+		}).Info("Resizing worker pool")
+		p.Resize(target)
+	})
+}
+
+// observe records the processing time of one event.
+func (p *Pool) observe(d time.Duration) {
+	p.latencyMu.Lock()
+	p.latencySum += d
+	p.latencyCount++
+	p.latencyMu.Unlock()
+}
+
+// takeLatency returns the mean processing time since the previous call.
+func (p *Pool) takeLatency() time.Duration {
+	p.latencyMu.Lock()
+	defer p.latencyMu.Unlock()
+	if p.latencyCount == 0 {
+		return 0
+	}
+	mean := p.latencySum / time.Duration(p.latencyCount)
+	p.latencySum, p.latencyCount = 0, 0
+	return mean
+}
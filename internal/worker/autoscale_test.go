@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scalingConfig() config.WorkerConfig {
+	cfg := config.Default().Worker
+	cfg.MinCount, cfg.MaxCount = 2, 10
+	cfg.ScaleUpBacklog, cfg.ScaleDownBacklog = 100, 10
+	cfg.LatencyTarget = 100 * time.Millisecond
+	return cfg
+}
+
+func TestScaler_GrowsOnBacklogAndLatency(t *testing.T) {
+	cfg := scalingConfig()
+	var sc scaler
+
+	target, reason := sc.decide(cfg, sample{size: 4, backlog: 4 * 150})
+	assert.Equal(t, 6, target)
+	assert.Equal(t, "backlog", reason)
+
+	target, reason = sc.decide(cfg, sample{size: 4, backlog: 50, latency: time.Second})
+	assert.Equal(t, 6, target)
+	assert.Equal(t, "latency", reason)
+
+	target, _ = sc.decide(cfg, sample{size: 9, backlog: 9 * 500})
+	assert.Equal(t, 10, target, "capped at max")
+
+	// Slow but with nothing queued: no point adding workers.
+	target, _ = sc.decide(cfg, sample{size: 4, latency: time.Second})
+	assert.Equal(t, 4, target)
+}
+
+func TestScaler_ShrinksOnlyAfterSustainedIdle(t *testing.T) {
+	cfg := scalingConfig()
+	var sc scaler
+
+	for i := 1; i < scaleDownSamples; i++ {
+		target, _ := sc.decide(cfg, sample{size: 4})
+		assert.Equal(t, 4, target, "sample %d", i)
+	}
+	target, reason := sc.decide(cfg, sample{size: 4})
+	assert.Equal(t, 3, target)
+	assert.Equal(t, "idle", reason)
+
+	// Backlog between the thresholds holds the size and resets the streak.
+	for i := 1; i < scaleDownSamples; i++ {
+		sc.decide(cfg, sample{size: 3})
+	}
+	target, _ = sc.decide(cfg, sample{size: 3, backlog: 3 * 50})
+	assert.Equal(t, 3, target)
+	target, _ = sc.decide(cfg, sample{size: 3})
+	assert.Equal(t, 3, target)
+
+	for i := 0; i < 10*scaleDownSamples; i++ {
+		target, _ = sc.decide(cfg, sample{size: 2})
+	}
+	assert.Equal(t, 2, target, "never below min")
+}
+
+func TestScaler_RestoresBounds(t *testing.T) {
+	cfg := scalingConfig()
+	var sc scaler
+
+	target, reason := sc.decide(cfg, sample{size: 1})
+	assert.Equal(t, 2, target)
+	assert.Equal(t, "min_bound", reason)
+
+	target, reason = sc.decide(cfg, sample{size: 12})
+	assert.Equal(t, 10, target)
+	assert.Equal(t, "max_bound", reason)
+}
+
+// panicQueue panics on the first reservation of each message.
+type panicQueue struct {
+	queue.Queue
+	panicked bool
+}
+
+func (q *panicQueue) Reserve(ctx context.Context) (queue.Message, error) {
+	if !q.panicked {
+		q.panicked = true
+		panic("boom")
+	}
+	return q.Queue.Reserve(ctx)
+}
+
+func TestPool_RestartsPanickedWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mem := queue.NewMemory()
+	pool := newTestPool(t, &panicQueue{Queue: mem}, 1)
+	pool.Start(ctx)
+
+	require.NoError(t, mem.Enqueue(ctx, []byte(`{"kind":"unknown"}`)))
+	require.Eventually(t, func() bool {
+		depth, err := mem.Depth(ctx)
+		return err == nil && depth.Ready == 0 && depth.InFlight == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, pool.Size())
+}
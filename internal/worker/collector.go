@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// collectTimeout bounds the Redis calls made for one scrape.
+const collectTimeout = 2 * time.Second
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		"event_queue_messages",
+		"Messages in the event queue by state: ready (click_queue), in_flight (click_processing) or dead (click_dead)",
+		[]string{"state"}, nil,
+	)
+	oldestAgeDesc = prometheus.NewDesc(
+		"event_queue_oldest_age_seconds",
+		"Age of the oldest ready event in the queue, 0 when the queue is empty",
+		nil, nil,
+	)
+	fallbackBytesDesc = prometheus.NewDesc(
+		"fallback_file_bytes",
+		"Size of the fallback file of events that could not be queued",
+		nil, nil,
+	)
+	fallbackRecordsDesc = prometheus.NewDesc(
+		"fallback_file_records",
+		"Events waiting in the fallback file",
+		nil, nil,
+	)
+)
+
+// PipelineCollector samples the event queue and fallback file when
+// Prometheus scrapes, so none of it costs anything on the request path.
+type PipelineCollector struct {
+	queue    queue.Queue
+	fallback *clicks.Fallback
+	logger   logrus.FieldLogger
+	now      func() time.Time
+}
+
+func NewPipelineCollector(q queue.Queue, fallback *clicks.Fallback, logger logrus.FieldLogger) *PipelineCollector {
+	return &PipelineCollector{queue: q, fallback: fallback, logger: logger, now: time.Now}
+}
+
+func (c *PipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- oldestAgeDesc
+	ch <- fallbackBytesDesc
+	ch <- fallbackRecordsDesc
+}
+
+// Collect reports what it can read; a metric whose source fails is reported
+// as invalid rather than as a misleading zero.
+func (c *PipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	if depth, err := c.queue.Depth(ctx); err != nil {
+		c.logger.WithError(err).Warn("Failed to read queue depth for metrics")
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth.Ready), "ready")
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth.InFlight), "in_flight")
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth.Dead), "dead")
+	}
+
+	if age, err := c.oldestAge(ctx); err != nil {
+		c.logger.WithError(err).Warn("Failed to read oldest queued event for metrics")
+		ch <- prometheus.NewInvalidMetric(oldestAgeDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(oldestAgeDesc, prometheus.GaugeValue, age.Seconds())
+	}
+
+	if size, records, err := c.fallback.Stats(); err != nil {
+		c.logger.WithError(err).Warn("Failed to read fallback file for metrics")
+		ch <- prometheus.NewInvalidMetric(fallbackBytesDesc, err)
+		ch <- prometheus.NewInvalidMetric(fallbackRecordsDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(fallbackBytesDesc, prometheus.GaugeValue, float64(size))
+		ch <- prometheus.MustNewConstMetric(fallbackRecordsDesc, prometheus.GaugeValue, float64(records))
+	}
+}
+
+// oldestAge is how long the next event to be reserved has been queued.
+// Events queued before enqueue times were recorded count as zero.
+func (c *PipelineCollector) oldestAge(ctx context.Context) (time.Duration, error) {
+	msg, err := c.queue.Peek(ctx)
+	if errors.Is(err, queue.ErrEmpty) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var wrapper clicks.RetryableClick
+	if err := json.Unmarshal(msg.Body, &wrapper); err != nil || wrapper.EnqueuedAt.IsZero() {
+		return 0, nil
+	}
+	return c.now().Sub(wrapper.EnqueuedAt), nil
+}
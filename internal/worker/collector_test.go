@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineCollector(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	q := queue.NewMemory()
+	for _, age := range []time.Duration{90 * time.Second, 10 * time.Second, 0} {
+		body, _ := json.Marshal(clicks.RetryableClick{Kind: clicks.KindClick, EnqueuedAt: now.Add(-age)})
+		require.NoError(t, q.Enqueue(ctx, body))
+	}
+	msg, err := q.Reserve(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.DeadLetter(ctx, msg, msg.Body))
+
+	fallback := clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl"))
+	require.NoError(t, os.WriteFile(fallback.Path, []byte("{}\n{}\n"), 0644))
+
+	c := NewPipelineCollector(q, fallback, logs.Discard())
+	c.now = func() time.Time { return now }
+
+	expected := `
+# HELP event_queue_messages Messages in the event queue by state: ready (click_queue), in_flight (click_processing) or dead (click_dead)
+# TYPE event_queue_messages gauge
+event_queue_messages{state="dead"} 1
+event_queue_messages{state="in_flight"} 0
+event_queue_messages{state="ready"} 2
+# HELP event_queue_oldest_age_seconds Age of the oldest ready event in the queue, 0 when the queue is empty
+# TYPE event_queue_oldest_age_seconds gauge
+event_queue_oldest_age_seconds 10
+# HELP fallback_file_bytes Size of the fallback file of events that could not be queued
+# TYPE fallback_file_bytes gauge
+fallback_file_bytes 6
+# HELP fallback_file_records Events waiting in the fallback file
+# TYPE fallback_file_records gauge
+fallback_file_records 2
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+}
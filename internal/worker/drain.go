@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+)
+
+// DrainReport describes what Drain finished and what it left behind.
+type DrainReport struct {
+	// Finished is true if every worker completed its event before the
+	// deadline.
+	Finished bool
+	// Requeued counts events interrupted at the deadline and put back on
+	// the queue.
+	Requeued int
+	// FlushErr and SyncErr are the errors of the final fallback flush and
+	// analytics sync; context.DeadlineExceeded if there was no time for them.
+	FlushErr error
+	SyncErr  error
+	// Fallback counts the events left in the fallback file.
+	Fallback int
+	// Queue is what is left on the queue for the next instance.
+	Queue    queue.Depth
+	QueueErr error
+}
+
+// Clean reports whether nothing was left undone. Ready events are not
+// counted: they wait for the next instance.
+func (r DrainReport) Clean() bool {
+	return r.Finished && r.Requeued == 0 && r.FlushErr == nil && r.SyncErr == nil &&
+		r.Fallback == 0 && r.QueueErr == nil && r.Queue.InFlight == 0
+}
+
+// isDraining reports whether Drain has been called. p.mu must be held.
+func (p *Pool) isDraining() bool {
+	if p.draining == nil {
+		return false
+	}
+	select {
+	case <-p.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// Drain shuts the pool down in order. Workers stop taking events and finish
+// the ones they hold; at ctx's deadline any still in progress are
+// interrupted and requeued unchanged. The autoscaler is stopped and
+// awaited, then the fallback file is flushed to the queue and, on the
+// leader, analytics are synced to Postgres one last time, if the deadline
+// allows. The scheduler must be stopped first so that these final runs do
+// not overlap the periodic ones.
+func (p *Pool) Drain(ctx context.Context) DrainReport {
+	p.mu.Lock()
+	if p.ctx == nil || p.isDraining() {
+		p.mu.Unlock()
+		return DrainReport{Finished: true}
+	}
+	close(p.draining)
+	p.stopJobs()
+	p.stops = nil
+	p.Metrics.poolSize.Set(0)
+	p.mu.Unlock()
+
+	var report DrainReport
+	workers := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(workers)
+	}()
+	select {
+	case <-workers:
+		report.Finished = true
+	case <-ctx.Done():
+		p.Logger.Warn("Shutdown deadline reached, interrupting workers")
+		p.abort()
+		<-workers
+	}
+	p.jobs.Wait()
+	report.Requeued = int(p.requeued.Load())
+
+	if err := ctx.Err(); err != nil {
+		report.FlushErr, report.SyncErr = err, err
+	} else {
+		report.FlushErr = p.FlushFallback(ctx)
+		if p.leads() {
+			report.SyncErr = SyncRedisAnalyticsToPostgres(ctx, p.Analytics, p.DB, p.Logger)
+		}
+	}
+
+	if _, records, err := p.Fallback.Stats(); err != nil {
+		report.FlushErr = fmt.Errorf("read fallback file: %w", err)
+	} else {
+		report.Fallback = records
+	}
+	report.Queue, report.QueueErr = p.Queue.Depth(context.WithoutCancel(ctx))
+	return report
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_DrainRequeuesAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := queue.NewMemory()
+	pool := newTestPool(t, q, 2)
+	// Processing only ends when the pool gives up on it.
+	pool.process = func(ctx context.Context, _ clicks.RetryableClick, _ logrus.FieldLogger) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	pool.Start(ctx)
+
+	body, _ := json.Marshal(clicks.RetryableClick{Kind: clicks.KindVideo, Retry: 1})
+	require.NoError(t, q.Enqueue(ctx, body))
+	require.Eventually(t, func() bool {
+		depth, err := q.Depth(ctx)
+		return err == nil && depth.InFlight == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer drainCancel()
+	report := pool.Drain(drainCtx)
+
+	assert.False(t, report.Finished)
+	assert.Equal(t, 1, report.Requeued)
+	assert.ErrorIs(t, report.SyncErr, context.DeadlineExceeded, "no time left for the final sync")
+	assert.Equal(t, queue.Depth{Ready: 1}, report.Queue)
+	assert.False(t, report.Clean())
+	assert.False(t, pool.Status(time.Minute).Running)
+
+	msg, err := q.Reserve(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(body), string(msg.Body), "requeued unchanged, without a retry")
+
+	pool.Resize(3)
+	assert.Equal(t, 0, pool.Size(), "a draining pool does not grow")
+}
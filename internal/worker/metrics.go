@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics report the work of a Pool.
+type Metrics struct {
+	poolSize         prometheus.Gauge
+	scalingDecisions *prometheus.CounterVec
+	workerRestarts   prometheus.Counter
+	eventsProcessed  *prometheus.CounterVec
+	eventOutcomes    *prometheus.CounterVec
+	eventLatency     *prometheus.HistogramVec
+	stageDuration    *prometheus.HistogramVec
+	workerState      *prometheus.GaugeVec
+}
+
+// NewMetrics returns a new set of the metrics; Register exposes them.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		poolSize: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "worker_pool_size",
+				Help: "Number of queue workers running",
+			},
+		),
+		scalingDecisions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "worker_scaling_decisions_total",
+				Help: "Worker pool resizes made by the autoscaler, by direction and reason",
+			},
+			[]string{"direction", "reason"},
+		),
+		workerRestarts: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "worker_restarts_total",
+				Help: "Workers restarted after a panic",
+			},
+		),
+		eventsProcessed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "worker_events_processed_total",
+				Help: "Events taken off the queue by the workers, by kind",
+			},
+			[]string{"kind"},
+		),
+		eventOutcomes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "worker_event_outcomes_total",
+				Help: "Processed events by kind, outcome (succeeded, retried, dead_lettered, discarded, requeued) and reason",
+			},
+			[]string{"kind", "outcome", "reason"},
+		),
+		eventLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "worker_event_latency_seconds",
+				Help:    "Time from an event occurring to its row being committed to Postgres, by kind",
+				Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600},
+			},
+			[]string{"kind"},
+		),
+		stageDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "worker_stage_duration_seconds",
+				Help:    "Time spent in each processing stage: dequeue, insert or analytics",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"stage"},
+		),
+		workerState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "worker_state",
+				Help: "1 for the state (busy or idle) each worker is in, 0 for the other",
+			},
+			[]string{"worker", "state"},
+		),
+	}
+}
+
+// Event outcomes and the reasons recorded with them.
+const (
+	outcomeSucceeded    = "succeeded"
+	outcomeRetried      = "retried"
+	outcomeDeadLettered = "dead_lettered"
+	outcomeDiscarded    = "discarded"
+	outcomeRequeued     = "requeued"
+
+	reasonOK             = "ok"
+	reasonInvalid        = "invalid"
+	reasonDuplicate      = "duplicate"
+	reasonError          = "error"
+	reasonPanic          = "panic"
+	reasonMalformed      = "malformed"
+	reasonMissingPayload = "missing_payload"
+	reasonUnknownKind    = "unknown_kind"
+	reasonShutdown       = "shutdown"
+)
+
+// Processing stages timed by worker_stage_duration_seconds.
+const (
+	stageDequeue   = "dequeue"
+	stageInsert    = "insert"
+	stageAnalytics = "analytics"
+)
+
+// observeStage records the time since start against stage.
+func (m *Metrics) observeStage(stage string, start time.Time) {
+	m.stageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
+
+// setBusy marks worker as busy or idle.
+func (m *Metrics) setBusy(worker string, busy bool) {
+	b, i := 0.0, 1.0
+	if busy {
+		b, i = 1, 0
+	}
+	m.workerState.WithLabelValues(worker, "busy").Set(b)
+	m.workerState.WithLabelValues(worker, "idle").Set(i)
+}
+
+// Register registers worker pool metrics with reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.poolSize,
+		m.scalingDecisions,
+		m.workerRestarts,
+		m.eventsProcessed,
+		m.eventOutcomes,
+		m.eventLatency,
+		m.stageDuration,
+		m.workerState,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
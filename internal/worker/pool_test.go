@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
+	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/conversions"
+	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T, q queue.Queue, count int) *Pool {
+	cfg := config.Default().Worker
+	cfg.Count = count
+	fallback := clicks.NewFallback(filepath.Join(t.TempDir(), "fallback.jsonl"))
+	return &Pool{Queue: q, Fallback: fallback, Metrics: NewMetrics(), Logger: logs.Discard(), Config: cfg, ReserveTimeout: time.Second}
+}
+
+func TestPool_ResizeKeepsProcessing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := queue.NewMemory()
+	pool := newTestPool(t, q, 2)
+	pool.Start(ctx)
+	assert.Equal(t, 2, pool.Size())
+
+	cfg := pool.Config
+	cfg.Count = 5
+	pool.Reload(cfg)
+	assert.Equal(t, 5, pool.Size())
+
+	// Events of an unknown kind are acked and discarded without touching
+	// Postgres or Redis.
+	for i := 0; i < 50; i++ {
+		require.NoError(t, q.Enqueue(ctx, []byte(`{"kind":"unknown"}`)))
+	}
+	pool.Resize(1)
+	assert.Equal(t, 1, pool.Size())
+
+	require.Eventually(t, func() bool {
+		depth, err := q.Depth(ctx)
+		return err == nil && depth.Ready == 0 && depth.InFlight == 0 && depth.Dead == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	done := make(chan struct{})
+	go func() { pool.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("workers did not exit")
+	}
+}
+
+func TestPool_RecordsOutcomes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := queue.NewMemory()
+	for _, body := range []string{`{"kind":"unknown"}`, `not json`, `{"kind":"video"}`} {
+		require.NoError(t, q.Enqueue(ctx, []byte(body)))
+	}
+	pool := newTestPool(t, q, 1)
+	metrics := pool.Metrics
+	discarded := func(kind, reason string) float64 {
+		return testutil.ToFloat64(metrics.eventOutcomes.WithLabelValues(kind, outcomeDiscarded, reason))
+	}
+	pool.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		depth, err := q.Depth(ctx)
+		return err == nil && depth.Ready == 0 && depth.InFlight == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1.0, discarded("unknown", reasonUnknownKind))
+	assert.Equal(t, 1.0, discarded("unknown", reasonMalformed))
+	assert.Equal(t, 1.0, discarded(clicks.KindVideo, reasonMissingPayload))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.eventsProcessed.WithLabelValues("unknown")))
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.workerState.WithLabelValues("0", "idle")) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.workerState.WithLabelValues("0", "busy")))
+}
+
+func TestPool_ApplyCountsRedeliveryOnce(t *testing.T) {
+	store := analytics.NewMemoryAnalytics()
+	pool := newTestPool(t, queue.NewMemory(), 1)
+	pool.Analytics = store
+	ctx := context.Background()
+
+	for _, want := range []string{reasonOK, reasonDuplicate} {
+		reason, err := pool.apply(ctx, pool.batch(clicks.KindClick, "c1").IncrementTotal("ad"), reasonOK, logs.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, want, reason)
+	}
+	total, err := store.GetTotalClicks(ctx, "ad")
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+// flakyAnalytics fails the first Apply.
+type flakyAnalytics struct {
+	*analytics.MemoryAnalytics
+	failed bool
+}
+
+func (f *flakyAnalytics) Apply(ctx context.Context, b *analytics.Batch) (bool, error) {
+	if !f.failed {
+		f.failed = true
+		return false, errors.New("redis unavailable")
+	}
+	return f.MemoryAnalytics.Apply(ctx, b)
+}
+
+func TestPool_CountsConversionRedeliveredAfterApplyFailed(t *testing.T) {
+	store := &flakyAnalytics{MemoryAnalytics: analytics.NewMemoryAnalytics()}
+	pool := newTestPool(t, queue.NewMemory(), 1)
+	pool.Analytics = store
+	ctx := context.Background()
+	conv := clicks.ConversionEvent{ID: conversions.NewID("order-1"), OrderID: "order-1", Value: 20}
+	attr := conversions.Attribution{Model: conversions.LastClick, AdID: "ad"}
+
+	_, err := pool.countConversion(ctx, conv, attr, true, logs.Discard())
+	require.Error(t, err, "the first delivery inserts the row, then Apply fails")
+
+	// Redeliveries find the row already stored.
+	for _, want := range []string{reasonOK, reasonDuplicate} {
+		reason, err := pool.countConversion(ctx, conv, attr, false, logs.Discard())
+		require.NoError(t, err)
+		assert.Equal(t, want, reason)
+	}
+	count, value, err := store.GetConversions(ctx, "ad")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 20.0, value)
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pricingTTL bounds how long a campaign price change takes to reach spend.
+const pricingTTL = 5 * time.Minute
+
+type cachedPricing struct {
+	pricing ads.Pricing
+	expires time.Time
+}
+
+// pricingCache keeps per-ad campaign pricing so that accruing spend does not
+// cost a query per impression. The zero value is an empty cache.
+type pricingCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPricing
+}
+
+func (p *pricingCache) get(ctx context.Context, db *pgxpool.Pool, adID string) (ads.Pricing, error) {
+	p.mu.Lock()
+	entry, ok := p.entries[adID]
+	p.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.pricing, nil
+	}
+
+	price, err := ads.GetPricing(ctx, db, adID)
+	if err != nil {
+		return ads.Pricing{}, err
+	}
+	p.mu.Lock()
+	if p.entries == nil {
+		p.entries = make(map[string]cachedPricing)
+	}
+	p.entries[adID] = cachedPricing{pricing: price, expires: time.Now().Add(pricingTTL)}
+	p.mu.Unlock()
+	return price, nil
+}
@@ -3,147 +3,595 @@ package worker
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Divyanth2468/video-ad-tracker/internal/ads"
 	"github.com/Divyanth2468/video-ad-tracker/internal/analytics"
 	"github.com/Divyanth2468/video-ad-tracker/internal/clicks"
-	"github.com/Divyanth2468/video-ad-tracker/internal/logs"
+	"github.com/Divyanth2468/video-ad-tracker/internal/config"
+	"github.com/Divyanth2468/video-ad-tracker/internal/conversions"
+	"github.com/Divyanth2468/video-ad-tracker/internal/fraud"
+	"github.com/Divyanth2468/video-ad-tracker/internal/queue"
+	"github.com/Divyanth2468/video-ad-tracker/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var logger = logs.Logger
-
-func StartQueueWorker(
-	ctx context.Context,
-	rdb *redis.Client,
-	db *pgxpool.Pool,
-	analytics *analytics.RedisAnalytics,
-	wg *sync.WaitGroup,
-	workerCount int,
-) {
-	// Periodic analytics sync goroutine
+// Pool runs the queue workers and the autoscaler, which sizes the pool
+// between Config.MinCount and Config.MaxCount from the queue backlog and
+// processing latency. Redis holds the impression records used for click
+// attribution. Config is the initial worker configuration, Config.Count the
+// initial pool size; Reload changes it while the pool runs. Drain shuts it
+// all down in order. The periodic fallback flush and analytics sync are
+// scheduler jobs (see FlushFallback and SyncRedisAnalyticsToPostgres);
+// Drain runs them one last time. With several replicas, only the one Leader
+// elects runs the analytics sync; the fallback file is local, so each
+// flushes its own.
+type Pool struct {
+	Queue     queue.Queue
+	Fallback  *clicks.Fallback
+	Redis     *redis.Client
+	DB        *pgxpool.Pool
+	Analytics analytics.AnalyticsStore
+	Detector  *fraud.Detector
+	Metrics   *Metrics
+	Logger    logrus.FieldLogger
+	Config    config.WorkerConfig
+	// Leader, if set, limits the final analytics sync to the elected replica.
+	Leader interface{ IsLeader() bool }
+	// Tracer, if set, records a span for each processed event.
+	Tracer trace.TracerProvider
+	// ReserveTimeout bounds each reserve call.
+	ReserveTimeout time.Duration
+
+	mu       sync.Mutex
+	ctx      context.Context
+	work     context.Context    // event processing, aborted by Drain at its deadline
+	abort    context.CancelFunc // cancels work
+	stopJobs context.CancelFunc // stops the autoscaler
+	draining chan struct{}      // closed by Drain: workers take no new events
+	stops    []chan struct{}    // one per running worker
+	nextID   int
+	beats    map[string]*heartbeat // by worker ID
+	resets   []chan struct{}       // wake the periodic jobs to pick up new intervals
+	wg       sync.WaitGroup        // workers
+	jobs     sync.WaitGroup        // the autoscaler
+	requeued atomic.Int64          // events put back by aborted workers
+	pricing  pricingCache          // campaign pricing of the ads whose spend accrues
+
+	// process applies an event; nil means processEvent.
+	process func(context.Context, clicks.RetryableClick, logrus.FieldLogger) (string, error)
+
+	latencyMu    sync.Mutex
+	latencySum   time.Duration
+	latencyCount int
+}
+
+// Start starts the workers and the autoscaler. They run until Drain is
+// called or ctx is cancelled; Wait blocks until the workers have finished.
+func (p *Pool) Start(ctx context.Context) {
+	jobs, stopJobs := context.WithCancel(ctx)
+	p.mu.Lock()
+	if p.process == nil {
+		p.process = p.processEvent
+	}
+	p.ctx = ctx
+	p.work, p.abort = context.WithCancel(ctx)
+	p.stopJobs = stopJobs
+	p.draining = make(chan struct{})
+	p.mu.Unlock()
+
+	p.Resize(p.settings().Count)
+	p.autoscale(jobs)
+}
+
+// Reload applies cfg to the running pool: retry limits apply to the next
+// failed event, the scale interval restarts its timer and the pool is
+// resized to cfg.Count, from where the autoscaler carries on within the new
+// bounds.
+func (p *Pool) Reload(cfg config.WorkerConfig) {
+	p.mu.Lock()
+	p.Config = cfg
+	for _, reset := range p.resets {
+		select {
+		case reset <- struct{}{}:
+		default:
+		}
+	}
+	p.mu.Unlock()
+
+	p.Resize(cfg.Count)
+}
+
+// Resize grows or shrinks the pool to n workers. Workers being removed
+// finish the event they hold before exiting, so nothing in flight is lost.
+// Once the pool is draining it no longer grows.
+func (p *Pool) Resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx == nil {
+		return
+	}
+	if p.isDraining() {
+		n = 0
+	}
+	for len(p.stops) < n {
+		stop := make(chan struct{})
+		p.stops = append(p.stops, stop)
+		p.wg.Add(1)
+		go p.run(p.work, p.nextID, stop)
+		p.nextID++
+	}
+	for len(p.stops) > n {
+		last := len(p.stops) - 1
+		close(p.stops[last])
+		p.stops = p.stops[:last]
+	}
+	p.Metrics.poolSize.Set(float64(len(p.stops)))
+}
+
+// Size returns the number of workers.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.stops)
+}
+
+// Wait blocks until every worker has exited.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// leads reports whether this replica runs the singleton jobs.
+func (p *Pool) leads() bool {
+	return p.Leader == nil || p.Leader.IsLeader()
+}
+
+func (p *Pool) settings() config.WorkerConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Config
+}
+
+// every runs job at the interval taken from the pool's settings until ctx is
+// cancelled, restarting the timer when Reload changes the settings.
+func (p *Pool) every(ctx context.Context, name string, interval func(config.WorkerConfig) time.Duration, job func()) {
+	reset := make(chan struct{}, 1)
+	p.mu.Lock()
+	p.resets = append(p.resets, reset)
+	p.mu.Unlock()
+
+	p.jobs.Add(1)
 	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
+		defer p.jobs.Done()
+		timer := time.NewTimer(interval(p.settings()))
+		defer timer.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
-				logger.Info("Analytics sync stopped due to context cancellation")
+				p.Logger.Info(name + " stopped due to context cancellation")
 				return
-			case <-ticker.C:
-				err := SyncRedisAnalyticsToPostgres(analytics, db)
-				if err != nil {
-					logger.WithError(err).Error("Periodic sync to Postgres failed")
-				}
+			case <-reset:
+				timer.Stop()
+			case <-timer.C:
+				job()
 			}
+			timer.Reset(interval(p.settings()))
 		}
 	}()
+}
 
-	// Periodic flush of fallback disk to Redis
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
+// run keeps a worker serving until ctx is cancelled or stop is closed,
+// restarting it if it panics.
+func (p *Pool) run(ctx context.Context, workerID int, stop <-chan struct{}) {
+	defer p.wg.Done()
+	id := strconv.Itoa(workerID)
+	logger := p.Logger.WithField("worker", workerID)
+	logger.Info("Worker started")
 
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Info("Fallback flush stopped due to context cancellation")
-				return
-			case <-ticker.C:
-				flushFallbackToRedis(ctx, rdb)
+	hb := &heartbeat{id: id, metrics: p.Metrics}
+	hb.mark(false)
+	p.mu.Lock()
+	if p.beats == nil {
+		p.beats = make(map[string]*heartbeat)
+	}
+	p.beats[id] = hb
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.beats, id)
+		p.mu.Unlock()
+		p.Metrics.workerState.DeleteLabelValues(id, "busy")
+		p.Metrics.workerState.DeleteLabelValues(id, "idle")
+	}()
+
+	for !p.serve(ctx, hb, stop, logger) {
+		p.Metrics.workerRestarts.Inc()
+		logger.Warn("Worker restarted after panic")
+	}
+}
+
+// serve processes events until ctx is cancelled, stop is closed or the pool
+// drains, then returns true. If processing panics, the event is retried like
+// a failed one and serve returns false. An event interrupted by ctx is put
+// back on the queue as it was.
+func (p *Pool) serve(ctx context.Context, hb *heartbeat, stop <-chan struct{}, logger logrus.FieldLogger) (exited bool) {
+	// Settle reserved events even once ctx is cancelled.
+	settle := context.WithoutCancel(ctx)
+	var current *queue.Message
+	var wrapper clicks.RetryableClick
+	var span trace.Span
+	defer func() {
+		if r := recover(); r != nil {
+			logger.WithField("panic", r).Errorf("Worker panicked: %s", debug.Stack())
+			if current != nil {
+				err := fmt.Errorf("panic: %v", r)
+				p.fail(ctx, *current, wrapper, reasonPanic, err, logger)
+				tracing.Fail(span, err)
+				span.End()
 			}
+			hb.mark(false)
+			exited = false
 		}
 	}()
 
-	// Start queue workers
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			log.Printf("[Worker %d] Started", workerID)
-
-			for {
-				select {
-				case <-ctx.Done():
-					log.Printf("[Worker %d] Shutdown signal received. Exiting...", workerID)
-					return
-				default:
-					if err := ensureRedisConnected(rdb); err != nil {
-						log.Printf("[Worker %d] Redis not reachable: %v", workerID, err)
-						time.Sleep(3 * time.Second)
-						continue
-					}
-
-					redisCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-					data, err := rdb.RPopLPush(redisCtx, "click_queue", "click_processing").Result()
-					cancel()
-
-					if err != nil {
-						if err != redis.Nil {
-							log.Printf("[Worker %d] RPOPLPUSH error: %v", workerID, err)
-						}
-						time.Sleep(1 * time.Second)
-						continue
-					}
-
-					var wrapper clicks.RetryableClick
-					if err := json.Unmarshal([]byte(data), &wrapper); err != nil {
-						log.Printf("[Worker %d] JSON unmarshal failed: %v. Discarding.", workerID, err)
-						_ = rdb.LRem(ctx, "click_processing", 0, data)
-						continue
-					}
-
-					err = clicks.InsertClickEvent(ctx, db, wrapper.Event)
-					if err != nil {
-						log.Printf("[Worker %d] DB insert failed for AdID %s: %v", workerID, wrapper.Event.AdID, err)
-						wrapper.Retry++
-						if wrapper.Retry >= 3 {
-							dlqData, _ := json.Marshal(wrapper)
-							_ = rdb.LPush(ctx, "click_dead", dlqData)
-						} else {
-							retryData, _ := json.Marshal(wrapper)
-							_ = rdb.LPush(ctx, "click_queue", retryData)
-						}
-						_ = rdb.LRem(ctx, "click_processing", 0, data)
-						time.Sleep(2 * time.Second)
-						continue
-					}
-
-					if err := analytics.IncrementTotal(wrapper.Event.AdID); err != nil {
-						log.Printf("[Worker %d] IncrementTotal failed: %v", workerID, err)
-					}
-					if err := analytics.AddUnique(wrapper.Event.AdID, wrapper.Event.IPAddress); err != nil {
-						log.Printf("[Worker %d] AddUnique failed: %v", workerID, err)
-					}
-					if err := analytics.IncrementHourly(wrapper.Event.AdID, wrapper.Event.Timestamp); err != nil {
-						log.Printf("[Worker %d] IncrementHourly failed: %v", workerID, err)
-					}
-
-					_ = rdb.LRem(ctx, "click_processing", 0, data)
-				}
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutdown signal received. Exiting...")
+			return true
+		case <-stop:
+			logger.Info("Worker removed from pool. Exiting...")
+			return true
+		case <-p.draining:
+			logger.Info("Pool draining. Exiting...")
+			return true
+		default:
+			hb.mark(false)
+			reserveCtx, cancel := context.WithTimeout(ctx, p.ReserveTimeout)
+			start := time.Now()
+			msg, err := p.Queue.Reserve(reserveCtx)
+			cancel()
+
+			if errors.Is(err, queue.ErrEmpty) {
+				p.pause(ctx, 1*time.Second)
+				continue
 			}
-		}(i)
+			if err != nil {
+				logger.WithError(err).Warn("Queue not reachable")
+				p.pause(ctx, 3*time.Second)
+				continue
+			}
+			reserved := time.Now()
+			p.Metrics.observeStage(stageDequeue, start)
+
+			hb.mark(true)
+			wrapper = clicks.RetryableClick{}
+			if err := json.Unmarshal(msg.Body, &wrapper); err != nil {
+				logger.WithError(err).Warn("Discarding malformed event")
+				p.Metrics.eventsProcessed.WithLabelValues("unknown").Inc()
+				p.Metrics.eventOutcomes.WithLabelValues("unknown", outcomeDiscarded, reasonMalformed).Inc()
+				_ = p.Queue.Ack(settle, msg)
+				hb.mark(false)
+				continue
+			}
+
+			kind := wrapper.EventKind()
+			p.Metrics.eventsProcessed.WithLabelValues(kind).Inc()
+			var spanCtx context.Context
+			spanCtx, span = p.startProcessSpan(ctx, wrapper, start, reserved)
+			current = &msg
+			start = time.Now()
+			reason, err := p.process(spanCtx, wrapper, logger)
+			p.observe(time.Since(start))
+			current = nil
+
+			var discard discardError
+			switch {
+			case errors.As(err, &discard):
+				logger.WithFields(logrus.Fields{"kind": wrapper.Kind, "reason": discard.reason}).Warn("Discarding event")
+				p.Metrics.eventOutcomes.WithLabelValues(kind, outcomeDiscarded, discard.reason).Inc()
+				span.SetAttributes(attribute.String("event.outcome", outcomeDiscarded))
+				_ = p.Queue.Ack(settle, msg)
+			case err != nil && ctx.Err() != nil:
+				tracing.Fail(span, err)
+				p.requeue(settle, msg, kind, logger)
+			case err != nil:
+				tracing.Fail(span, err)
+				p.fail(ctx, msg, wrapper, reasonError, err, logger)
+			default:
+				p.Metrics.eventOutcomes.WithLabelValues(kind, outcomeSucceeded, reason).Inc()
+				span.SetAttributes(attribute.String("event.outcome", outcomeSucceeded))
+				_ = p.Queue.Ack(settle, msg)
+			}
+			span.End()
+			hb.mark(false)
+		}
+	}
+}
+
+// startProcessSpan starts the span covering an event from the moment its
+// worker began waiting for it, linked to the span that queued it, with the
+// wait recorded as a queue.dequeue child.
+func (p *Pool) startProcessSpan(ctx context.Context, wrapper clicks.RetryableClick, waiting, reserved time.Time) (context.Context, trace.Span) {
+	producer := trace.LinkFromContext(tracing.Extract(ctx, wrapper.TraceContext))
+	ctx, span := tracing.Tracer(p.Tracer).Start(ctx, "worker.process "+wrapper.EventKind(),
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(producer),
+		trace.WithTimestamp(waiting),
+		trace.WithAttributes(
+			attribute.String("event.kind", wrapper.EventKind()),
+			attribute.String("ad.id", wrapper.AdID()),
+			attribute.Int("event.retry", wrapper.Retry),
+		),
+	)
+	_, dequeue := tracing.Start(ctx, "queue.dequeue", trace.WithTimestamp(waiting))
+	dequeue.End(trace.WithTimestamp(reserved))
+	return ctx, span
+}
+
+// fail sends a failed event back to the queue, or to its dead letters once
+// retries are exhausted, then backs off until ctx is cancelled.
+func (p *Pool) fail(ctx context.Context, msg queue.Message, wrapper clicks.RetryableClick, reason string, err error, logger logrus.FieldLogger) {
+	settle := context.WithoutCancel(ctx)
+	cfg := p.settings()
+	kind := wrapper.EventKind()
+	wrapper.Retry++
+	logger = logger.WithFields(logrus.Fields{"kind": kind, "adID": wrapper.AdID(), "retry": wrapper.Retry}).WithError(err)
+
+	data, _ := json.Marshal(wrapper)
+	if wrapper.Retry >= cfg.MaxRetries {
+		logger.Error("Processing event failed, moving it to dead letters")
+		p.Metrics.eventOutcomes.WithLabelValues(kind, outcomeDeadLettered, reason).Inc()
+		_ = p.Queue.DeadLetter(settle, msg, data)
+	} else {
+		logger.Warn("Processing event failed, retrying")
+		p.Metrics.eventOutcomes.WithLabelValues(kind, outcomeRetried, reason).Inc()
+		_ = p.Queue.Nack(settle, msg, data)
+	}
+	p.pause(ctx, cfg.RetryBackoff)
+}
+
+// requeue puts an event whose processing was interrupted by shutdown back
+// on the queue unchanged; the interruption does not count as a retry.
+func (p *Pool) requeue(ctx context.Context, msg queue.Message, kind string, logger logrus.FieldLogger) {
+	if err := p.Queue.Nack(ctx, msg, msg.Body); err != nil {
+		logger.WithError(err).Error("Failed to requeue interrupted event")
+		return
+	}
+	logger.WithField("kind", kind).Warn("Requeued event interrupted by shutdown")
+	p.Metrics.eventOutcomes.WithLabelValues(kind, outcomeRequeued, reasonShutdown).Inc()
+	p.requeued.Add(1)
+}
+
+// pause waits for d, cutting it short when ctx is cancelled or the pool
+// drains.
+func (p *Pool) pause(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-p.draining:
+	}
+}
+
+// discardError reports an event that can never be processed. It is acked
+// rather than retried.
+type discardError struct {
+	reason string
+}
+
+func (e discardError) Error() string {
+	return "event discarded: " + e.reason
+}
+
+// committed records the end-to-end latency of an event whose row has just
+// been written.
+func (p *Pool) committed(wrapper clicks.RetryableClick) {
+	if at := wrapper.OccurredAt(); !at.IsZero() {
+		p.Metrics.eventLatency.WithLabelValues(wrapper.EventKind()).Observe(time.Since(at).Seconds())
 	}
 }
 
-func ensureRedisConnected(rdb *redis.Client) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	return rdb.Ping(ctx).Err()
+// batch starts the analytics updates of the event of the given kind and ID.
+func (p *Pool) batch(kind, id string) *analytics.Batch {
+	return analytics.NewBatch(kind+":"+id, p.settings().DedupTTL)
 }
 
-func flushFallbackToRedis(ctx context.Context, rdb *redis.Client) {
-	file, err := os.Open("fallback_clicks.jsonl")
+// apply makes an event's analytics updates in one step, once per event: a
+// redelivered event whose updates were already made changes nothing and is
+// reported as a duplicate. Otherwise it returns reason. A failure leaves no
+// update behind, so the event can safely be retried.
+func (p *Pool) apply(ctx context.Context, batch *analytics.Batch, reason string, logger logrus.FieldLogger) (string, error) {
+	start := time.Now()
+	defer p.Metrics.observeStage(stageAnalytics, start)
+	applied, err := p.Analytics.Apply(ctx, batch)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			logger.WithError(err).Error("Failed to open fallback file")
+		return "", err
+	}
+	if !applied {
+		logger.Info("Analytics already applied, redelivered event not counted again")
+		return reasonDuplicate, nil
+	}
+	return reason, nil
+}
+
+// countConversion counts an attributed conversion. It runs whether or not
+// the conversion's row was just inserted, as the row may be left by a
+// delivery whose counters failed. The batch is keyed on the conversion ID,
+// which is derived from the order, so repeated postbacks for an order are
+// counted once.
+func (p *Pool) countConversion(ctx context.Context, conv clicks.ConversionEvent, attr conversions.Attribution, inserted bool, logger logrus.FieldLogger) (string, error) {
+	if attr.AdID == "" {
+		if !inserted {
+			return reasonDuplicate, nil
 		}
-		return
+		return reasonOK, nil
+	}
+	batch := p.batch(clicks.KindConversion, conv.ID).IncrementConversion(attr.AdID, conv.Value)
+	return p.apply(ctx, batch, reasonOK, logger)
+}
+
+// processEvent applies a single queued event and returns the reason recorded
+// with its success. A discardError acks the event unprocessed; any other
+// error sends it back to the queue, or to its dead letters once retries are
+// exhausted.
+func (p *Pool) processEvent(ctx context.Context, wrapper clicks.RetryableClick, logger logrus.FieldLogger) (string, error) {
+	rdb, db, detector := p.Redis, p.DB, p.Detector
+	switch wrapper.EventKind() {
+	case clicks.KindImpression:
+		if wrapper.Impression == nil {
+			return "", discardError{reasonMissingPayload}
+		}
+		imp := *wrapper.Impression
+		logger = logger.WithFields(logrus.Fields{"kind": clicks.KindImpression, "adID": imp.AdID})
+		// Impressions replayed from the fallback file never reached Redis.
+		if err := clicks.RecordImpression(ctx, rdb, imp); err != nil {
+			logger.WithError(err).Warn("RecordImpression failed")
+		}
+		start := time.Now()
+		if err := clicks.InsertImpression(ctx, db, imp); err != nil {
+			return "", err
+		}
+		p.Metrics.observeStage(stageInsert, start)
+		p.committed(wrapper)
+
+		// Once applied the event cannot add spend, so a failed pricing
+		// lookup is retried rather than applied without it.
+		price, err := p.pricing.get(ctx, db, imp.AdID)
+		if err != nil {
+			return "", err
+		}
+		batch := p.batch(clicks.KindImpression, imp.ID).
+			IncrementImpression(imp.AdID).
+			AddSpend(imp.AdID, price.CPM/1000)
+		if imp.ExperimentID != "" {
+			batch.IncrementArmImpression(imp.ExperimentID, imp.Arm)
+		}
+		return p.apply(ctx, batch, reasonOK, logger)
+
+	case clicks.KindVideo:
+		if wrapper.Video == nil {
+			return "", discardError{reasonMissingPayload}
+		}
+		logger = logger.WithFields(logrus.Fields{"kind": clicks.KindVideo, "adID": wrapper.Video.AdID})
+		start := time.Now()
+		if err := clicks.InsertVideoEvent(ctx, db, *wrapper.Video); err != nil {
+			return "", err
+		}
+		p.Metrics.observeStage(stageInsert, start)
+		p.committed(wrapper)
+
+		batch := p.batch(clicks.KindVideo, wrapper.Video.ID).IncrementVideoEvent(wrapper.Video.AdID, wrapper.Video.Type)
+		return p.apply(ctx, batch, reasonOK, logger)
+
+	case clicks.KindClick:
+		// Fraud scoring runs before the click is persisted so the score and
+		// reasons are stored on the row; invalid clicks are not billable.
+		event := wrapper.Event
+		logger = logger.WithFields(logrus.Fields{"kind": clicks.KindClick, "adID": event.AdID, "clickID": event.ID})
+		imp, err := clicks.LookupImpression(ctx, rdb, event.ImpressionID)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		if imp != nil && imp.AdID == event.AdID {
+			// The click may overtake its impression in the queue; make sure
+			// the row it references exists.
+			if err := clicks.InsertImpression(ctx, db, *imp); err != nil {
+				return "", err
+			}
+		} else {
+			event.ImpressionID = ""
+		}
+
+		result := detector.Score(ctx, event, imp)
+		event.FraudScore, event.FraudReasons, event.Invalid = result.Score, result.Reasons, !result.Valid
+
+		if err := clicks.InsertClickEvent(ctx, db, event); err != nil {
+			return "", err
+		}
+		p.Metrics.observeStage(stageInsert, start)
+		p.committed(wrapper)
+
+		batch, reason := p.batch(clicks.KindClick, event.ID), reasonOK
+		if event.Invalid {
+			logger.WithFields(logrus.Fields{"score": event.FraudScore, "reasons": event.FraudReasons}).Info("Click flagged invalid")
+			batch.IncrementInvalid(event.AdID)
+			reason = reasonInvalid
+		} else {
+			price, err := p.pricing.get(ctx, db, event.AdID)
+			if err != nil {
+				return "", err
+			}
+			batch.IncrementTotal(event.AdID).
+				AddUnique(event.AdID, event.IPAddress).
+				IncrementHourly(event.AdID, event.Timestamp).
+				AddSpend(event.AdID, price.CPC)
+			if event.ExperimentID != "" {
+				batch.IncrementArmClick(event.ExperimentID, event.Arm)
+			}
+		}
+		reason, err = p.apply(ctx, batch, reason, logger)
+		if err == nil && reason != reasonDuplicate {
+			detector.Record(result)
+		}
+		return reason, err
+
+	case clicks.KindConversion:
+		if wrapper.Conversion == nil {
+			return "", discardError{reasonMissingPayload}
+		}
+		conv := *wrapper.Conversion
+		logger = logger.WithFields(logrus.Fields{"kind": clicks.KindConversion, "conversionID": conv.ID})
+		attr, err := conversions.Attribute(ctx, db, conv)
+		if err != nil {
+			return "", err
+		}
+		start := time.Now()
+		inserted, err := conversions.InsertConversion(ctx, db, conv, attr)
+		if err != nil {
+			return "", err
+		}
+		p.Metrics.observeStage(stageInsert, start)
+		if inserted {
+			p.committed(wrapper)
+			logger.WithFields(logrus.Fields{"model": attr.Model, "adID": attr.AdID}).Info("Conversion attributed")
+		}
+		return p.countConversion(ctx, conv, attr, inserted, logger)
+
+	default:
+		return "", discardError{reasonUnknownKind}
+	}
+}
+
+// FlushFallback re-queues events that were written to the fallback file
+// while the queue was unreachable. Failures are logged as well as returned.
+func (p *Pool) FlushFallback(ctx context.Context) error {
+	logger := p.Logger
+
+	file, err := os.Open(p.Fallback.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		logger.WithError(err).Error("Failed to open fallback file")
+		return err
 	}
 	defer file.Close()
 
@@ -158,7 +606,7 @@ func flushFallbackToRedis(ctx context.Context, rdb *redis.Client) {
 		}
 
 		data, _ := json.Marshal(wrapper)
-		if err := rdb.LPush(ctx, "click_queue", data).Err(); err != nil {
+		if err := p.Queue.Enqueue(ctx, data); err != nil {
 			logger.WithError(err).Error("Failed to requeue fallback event")
 			unprocessed = append(unprocessed, wrapper) // keep for retry
 		}
@@ -168,10 +616,10 @@ func flushFallbackToRedis(ctx context.Context, rdb *redis.Client) {
 
 	// If some events couldn't be re-queued, rewrite them
 	if len(unprocessed) > 0 {
-		f, err := os.Create("fallback_clicks.jsonl")
+		f, err := os.Create(p.Fallback.Path)
 		if err != nil {
 			logger.WithError(err).Error("Failed to rewrite fallback file")
-			return
+			return err
 		}
 		defer f.Close()
 
@@ -181,45 +629,61 @@ func flushFallbackToRedis(ctx context.Context, rdb *redis.Client) {
 				logger.WithError(err).Error("Failed to encode event while rewriting fallback file")
 			}
 		}
-	} else {
-		// All events successfully pushed, delete the file
-		if err := os.Remove("fallback_clicks.jsonl"); err != nil {
-			logger.WithError(err).Error("Failed to delete fallback file")
-		}
+		return fmt.Errorf("%d fallback events could not be queued", len(unprocessed))
+	}
+
+	// All events successfully pushed, delete the file
+	if err := os.Remove(p.Fallback.Path); err != nil {
+		logger.WithError(err).Error("Failed to delete fallback file")
+		return err
 	}
+	return nil
 }
 
-func SyncRedisAnalyticsToPostgres(redis *analytics.RedisAnalytics, db *pgxpool.Pool) error {
-	adIDs := []string{
-		"11111111-1111-1111-1111-111111111111",
-		"22222222-2222-2222-2222-222222222222",
-		"33333333-3333-3333-3333-333333333333",
+// SyncRedisAnalyticsToPostgres copies the analytics of every ad in the ads
+// table from Redis to ad_analytics. An ad that fails is logged and skipped;
+// the errors are returned together.
+func SyncRedisAnalyticsToPostgres(ctx context.Context, redis analytics.AnalyticsStore, db *pgxpool.Pool, logger logrus.FieldLogger) error {
+	list, err := ads.ListAds(ctx, db)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list ads for analytics sync")
+		return fmt.Errorf("list ads: %w", err)
 	}
 
-	for _, adID := range adIDs {
-		data, err := redis.GetAnalytics(adID, "1h")
+	var errs []error
+	for _, ad := range list {
+		adID := ad.ID
+		data, err := redis.GetAnalytics(ctx, adID, "1h")
 		if err != nil {
 			logger.WithField("adID", adID).WithError(err).Error("Failed to fetch analytics for sync")
+			errs = append(errs, fmt.Errorf("fetch analytics for %s: %w", adID, err))
 			continue
 		}
 
-		_, err = db.Exec(context.Background(), `
-			INSERT INTO ad_analytics (ad_id, total_clicks, unique_clicks, impressions, ctr, updated_at)
-			VALUES ($1, $2, $3, $4, $5, NOW())
+		_, err = db.Exec(ctx, `
+			INSERT INTO ad_analytics (ad_id, total_clicks, unique_clicks, impressions, ctr,
+				conversions, conversion_value, conversion_rate, cost_per_conversion, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
 			ON CONFLICT (ad_id) DO UPDATE SET
 				total_clicks = EXCLUDED.total_clicks,
 				unique_clicks = EXCLUDED.unique_clicks,
 				impressions = EXCLUDED.impressions,
 				ctr = EXCLUDED.ctr,
+				conversions = EXCLUDED.conversions,
+				conversion_value = EXCLUDED.conversion_value,
+				conversion_rate = EXCLUDED.conversion_rate,
+				cost_per_conversion = EXCLUDED.cost_per_conversion,
 				updated_at = NOW()
-		`, adID, data["totalClicks"], data["uniqueClicks"], data["impressions"], data["ctr"])
+		`, adID, data["totalClicks"], data["uniqueClicks"], data["impressions"], data["ctr"],
+			data["conversions"], data["conversionValue"], data["conversionRate"], data["costPerConversion"])
 
 		if err != nil {
 			logger.WithField("adID", adID).WithError(err).Error("Failed to sync analytics to DB")
+			errs = append(errs, fmt.Errorf("sync analytics for %s: %w", adID, err))
 			continue
 		}
 		logger.WithField("adID", adID).Info("Synced analytics to DB")
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeat is a worker's last sign of life, read by Status.
+type heartbeat struct {
+	id      string
+	metrics *Metrics
+	at      atomic.Int64 // unix nanoseconds
+	busy    atomic.Bool
+}
+
+// mark records that the worker is alive and whether it holds an event.
+func (h *heartbeat) mark(busy bool) {
+	h.at.Store(time.Now().UnixNano())
+	h.busy.Store(busy)
+	h.metrics.setBusy(h.id, busy)
+}
+
+// Status describes the running pool.
+type Status struct {
+	Running bool `json:"running"`
+	Size    int  `json:"size"`
+	Busy    int  `json:"busy"`
+	// Stalled lists the workers that have shown no sign of life within the
+	// stall timeout, e.g. because an event has hung their processing.
+	Stalled []string `json:"stalled,omitempty"`
+}
+
+// Status reports the pool's workers, counting those silent for longer than
+// stall as stalled. Idle workers check in at least once per reserve timeout.
+func (p *Pool) Status(stall time.Duration) Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := Status{Running: p.ctx != nil && p.ctx.Err() == nil && !p.isDraining(), Size: len(p.stops)}
+	cutoff := time.Now().Add(-stall).UnixNano()
+	for id, h := range p.beats {
+		if h.busy.Load() {
+			s.Busy++
+		}
+		if h.at.Load() < cutoff {
+			s.Stalled = append(s.Stalled, id)
+		}
+	}
+	sort.Strings(s.Stalled)
+	return s
+}